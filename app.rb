@@ -47,10 +47,16 @@ def dashboard_data
     orphaned_statefiles: get_data_from_json_file("orphaned_statefiles", "data", ItemList),
     hosted_services: get_data_from_json_file("hosted_services", "namespace_details", ItemList),
     live_1_domains: get_data_from_json_file("live_1_domains", "live_1_domains", ItemList),
+    namespace_compliance: get_data_from_json_file("namespace_metadata_compliance", "namespace_compliance", ItemList),
   }
 
+  freshness = ReportFreshness.new(logger: logger)
+  stale_reports = freshness.stale_reports(info.values.to_h { |list| [list.name, list.updated_at] })
+  go_stale_count = freshness.go_stale_count
+  stale_count = stale_reports.length + go_stale_count.to_i
+
   updated_at = info.values.map(&:updated_at).min
-  todo_count = info.values.map(&:todo_count).sum
+  todo_count = info.values.map(&:todo_count).sum + stale_count
 
   {
     updated_at: updated_at,
@@ -61,7 +67,11 @@ def dashboard_data
         terraform_modules: info[:terraform_modules].todo_count,
         orphaned_resources: info[:orphaned_resources].todo_count,
         orphaned_statefiles: info[:orphaned_statefiles].todo_count,
+        namespace_compliance: info[:namespace_compliance].todo_count,
+        stale_reports: stale_count,
       },
+      stale_reports: stale_reports,
+      go_freshness_unavailable: go_stale_count.nil?,
       action_required: (todo_count > 0),
     },
   }
@@ -159,11 +169,6 @@ def hosted_services_for_namespace(namespace)
   ns.merge("updated_at" => DateTime.parse(data["updated_at"]))
 end
 
-def infra_deployments_from_json
-  json = store.retrieve_file("data/infrastructure_deployments.json")
-  InfraDeployments.new(json: json)
-end
-
 ############################################################
 
 get "/" do
@@ -236,20 +241,6 @@ get "/hosted_services" do
   end
 end
 
-get "/infrastructure_deployments" do
-  if accept_json?(request)
-    serve_json_data(:infrastructure_deployments)
-  else
-    infra = infra_deployments_from_json
-    locals = {
-      title: "Infrastructure deployments",
-      deployments: infra.deployments,
-      updated_at: infra.updated_at,
-    }
-    erb :infrastructure_deployments, locals: locals
-  end
-end
-
 get "/namespace_usage" do
   redirect "/namespace_usage_cpu"
 end
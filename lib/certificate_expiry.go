@@ -0,0 +1,154 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
+)
+
+type CertificateExpiry struct {
+	Certificates []Certificate `json:"certificates"`
+	LastUpdated  string
+	Total        int
+	Expired      int
+	Expiring     int
+	Errors       int
+	Cluster      string
+	Clusters     []string
+	Listing      Listing
+}
+
+// reportCertificate is embedded by Certificate under another name, as the report has a
+// Certificate field of its own
+type reportCertificate = hoodaw.Certificate
+
+type Certificate struct {
+	reportCertificate
+	DaysLeft     int    `json:"-"`
+	Team         string `json:"-"`
+	BusinessUnit string `json:"-"`
+	Slack        string `json:"-"`
+}
+
+// certificateExpiringDays is how many days before NotAfter a certificate is counted as expiring
+const certificateExpiringDays = 30
+
+// certificateColumns are the columns the certificates can be filtered and sorted by
+var certificateColumns = []column[Certificate]{
+	{name: "host", value: func(c Certificate) string { return c.Host }},
+	{name: "days_left", value: func(c Certificate) string { return strconv.Itoa(c.DaysLeft) }},
+	{name: "expires", value: func(c Certificate) string { return c.NotAfter }},
+	{name: "namespace", value: func(c Certificate) string { return c.Namespace }},
+	{name: "cluster", value: func(c Certificate) string { return c.Cluster }},
+	{name: "team", value: func(c Certificate) string { return c.Team }},
+	{name: "business_unit", value: func(c Certificate) string { return c.BusinessUnit }},
+	{name: "issuer", value: func(c Certificate) string { return c.Issuer }},
+	{name: "secret", value: func(c Certificate) string { return c.Secret }},
+}
+
+// CertificateExpiryPage lists the certificates of the ingress hosts, soonest to expire first
+// unless the query sorts them otherwise, with the team which owns their namespace
+func CertificateExpiryPage(w http.ResponseWriter, bucket string, query Query, format Format, client *s3.Client) error {
+	var expiry CertificateExpiry
+	byteValue, filestamp, err := readReport(s3Load(client), bucket, "certificate_expiry.json", &expiry)
+	if err != nil {
+		return err
+	}
+
+	expiry.LastUpdated = filestamp
+	expiry.Cluster = query.Filters["cluster"]
+
+	owners := loadNamespaceOwners(client, bucket)
+
+	clusters := make([]string, 0)
+	for i, c := range expiry.Certificates {
+		clusters = append(clusters, c.Cluster)
+		owner := owners.lookup(c.Cluster, c.Namespace)
+		expiry.Certificates[i].Team, expiry.Certificates[i].BusinessUnit, expiry.Certificates[i].Slack = owner.TeamName, owner.BusinessUnit, owner.SlackChannel
+	}
+	expiry.Clusters = filterOptions(clusters)
+
+	matching, err := queryRows(query, sortByExpiry(expiry.Certificates, time.Now()), certificateColumns)
+	if err != nil {
+		return err
+	}
+	expiry.Certificates, expiry.Listing = paginate(query, matching)
+
+	if format != FormatHTML {
+		return writeRows(w, format, "certificate_expiry", byteValue, "certificates", expiry.Certificates, expiry.Listing, certificateExpiryCSV)
+	}
+
+	expiry.Total = len(matching)
+	expiry.Expired, expiry.Expiring, expiry.Errors = countExpiry(matching)
+
+	return render(w, http.StatusOK, "certificate_expiry.html", expiry)
+}
+
+// countExpiry counts the certificates which have expired, those still to expire within
+// certificateExpiringDays and those which couldn't be read, each only once
+func countExpiry(certificates []Certificate) (expired, expiring, errors int) {
+	for _, c := range certificates {
+		switch {
+		case c.Error != "":
+			errors++
+		case c.DaysLeft < 0:
+			expired++
+		case c.DaysLeft < certificateExpiringDays:
+			expiring++
+		}
+	}
+
+	return expired, expiring, errors
+}
+
+// sortByExpiry sets the days left of each certificate and sorts them soonest to expire first.
+// Certificates which couldn't be read go first as nothing is known about when they expire.
+func sortByExpiry(certificates []Certificate, now time.Time) []Certificate {
+	for i, c := range certificates {
+		if c.Error != "" {
+			continue
+		}
+
+		notAfter, err := time.Parse(time.RFC3339, c.NotAfter)
+		if err != nil {
+			certificates[i].Error = fmt.Sprintf("invalid expiry %q", c.NotAfter)
+			continue
+		}
+		certificates[i].DaysLeft = int(math.Floor(notAfter.Sub(now).Hours() / 24))
+	}
+
+	sort.SliceStable(certificates, func(i, j int) bool {
+		a, b := certificates[i], certificates[j]
+		if (a.Error != "") != (b.Error != "") {
+			return a.Error != ""
+		}
+		if a.DaysLeft != b.DaysLeft {
+			return a.DaysLeft < b.DaysLeft
+		}
+		return a.Host < b.Host
+	})
+
+	return certificates
+}
+
+// certificateExpiryCSV is a row for each ingress host and its certificate
+func certificateExpiryCSV(data []byte) (csvTable, error) {
+	var expiry CertificateExpiry
+	if err := json.Unmarshal(data, &expiry); err != nil {
+		return csvTable{}, err
+	}
+
+	table := csvTable{header: []string{"cluster", "namespace", "host", "ingress", "secret", "certificate", "issuer", "issuer_ref", "sans", "not_after", "error"}}
+	for _, c := range expiry.Certificates {
+		table.rows = append(table.rows, []string{c.Cluster, c.Namespace, c.Host, c.Ingress, c.Secret, c.Certificate, c.Issuer, c.IssuerRef, joinCSV(c.SANs), c.NotAfter, c.Error})
+	}
+
+	return table, nil
+}
@@ -0,0 +1,49 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
+)
+
+func Test_sortByExpiry(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	certificates := []Certificate{
+		{reportCertificate: hoodaw.Certificate{Host: "later.service.justice.gov.uk", NotAfter: "2025-05-01T12:00:00Z"}},
+		{reportCertificate: hoodaw.Certificate{Host: "expired.service.justice.gov.uk", NotAfter: "2025-02-27T00:00:00Z"}},
+		{reportCertificate: hoodaw.Certificate{Host: "missing.service.justice.gov.uk", Error: "unable to read secret"}},
+		{reportCertificate: hoodaw.Certificate{Host: "soon.service.justice.gov.uk", NotAfter: "2025-03-10T12:00:00Z"}},
+		{reportCertificate: hoodaw.Certificate{Host: "invalid.service.justice.gov.uk", NotAfter: "tomorrow"}},
+	}
+
+	want := []Certificate{
+		{reportCertificate: hoodaw.Certificate{Host: "invalid.service.justice.gov.uk", NotAfter: "tomorrow", Error: `invalid expiry "tomorrow"`}},
+		{reportCertificate: hoodaw.Certificate{Host: "missing.service.justice.gov.uk", Error: "unable to read secret"}},
+		{reportCertificate: hoodaw.Certificate{Host: "expired.service.justice.gov.uk", NotAfter: "2025-02-27T00:00:00Z"}, DaysLeft: -3},
+		{reportCertificate: hoodaw.Certificate{Host: "soon.service.justice.gov.uk", NotAfter: "2025-03-10T12:00:00Z"}, DaysLeft: 9},
+		{reportCertificate: hoodaw.Certificate{Host: "later.service.justice.gov.uk", NotAfter: "2025-05-01T12:00:00Z"}, DaysLeft: 61},
+	}
+
+	if got := sortByExpiry(certificates, now); !reflect.DeepEqual(got, want) {
+		t.Errorf("sortByExpiry() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_countExpiry(t *testing.T) {
+	certificates := []Certificate{
+		{reportCertificate: hoodaw.Certificate{Error: "unable to read secret"}},
+		{DaysLeft: -3},
+		{DaysLeft: -1},
+		{DaysLeft: 0},
+		{DaysLeft: 29},
+		{DaysLeft: 30},
+	}
+
+	expired, expiring, errors := countExpiry(certificates)
+	if expired != 2 || expiring != 2 || errors != 1 {
+		t.Errorf("countExpiry() = %d expired, %d expiring and %d errors, want 2, 2 and 1", expired, expiring, errors)
+	}
+}
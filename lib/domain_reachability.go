@@ -0,0 +1,32 @@
+package lib
+
+import (
+	"sort"
+
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
+)
+
+type DomainReachability = hoodaw.DomainReachability
+
+// ReachableDomain is a domain checked by the domain-reachability report with the team which owns
+// its namespace
+type ReachableDomain struct {
+	hoodaw.ReachableDomain
+	Team string `json:"-"`
+}
+
+// danglingDomains returns the dangling domains of the report in the cluster, or every cluster when
+// it is empty, with the team which owns their namespace, sorted by hostname
+func danglingDomains(reachability DomainReachability, owners namespaceOwners, cluster string) []ReachableDomain {
+	dangling := make([]ReachableDomain, 0)
+	for _, d := range reachability.Domains {
+		if d.Status != "dangling" || !matchesFilter(cluster, d.Cluster) {
+			continue
+		}
+		dangling = append(dangling, ReachableDomain{ReachableDomain: d, Team: owners.lookup(d.Cluster, d.Namespace).TeamName})
+	}
+
+	sort.Slice(dangling, func(i, j int) bool { return dangling[i].Hostname < dangling[j].Hostname })
+
+	return dangling
+}
@@ -2,54 +2,436 @@ package lib
 
 import (
 	"encoding/json"
-	"fmt"
+	"log/slog"
 	"net/http"
-	"text/template"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
 )
 
+// erroringNamespacesKey is the report of the namespaces which failed in the last apply-live run
+const erroringNamespacesKey = "apply-live/gathered-namespaces-errors.json"
+
+// erroringNamespacesHistoryKey is the erroring-namespaces-history report in the hoodaw bucket
+const erroringNamespacesHistoryKey = "erroring_namespaces_history.json"
+
 type NamespaceError struct {
-	Namespace string `json:"namespace"`
-	Error     string `json:"error"`
-	BuildID	  int `json:"build_id"`
+	hoodaw.NamespaceError
+	BuildURL     string `json:"-"`
+	Team         string `json:"-"`
+	BusinessUnit string `json:"-"`
+	Slack        string `json:"-"`
 }
 
 type ErroringNamespaces struct {
 	Namespaces  []NamespaceError `json:"namespaces"`
+	Groups      []ErrorGroup
+	History     []NamespaceHistory
+	Runs        []string
+	Trends      map[string]ErrorTrend
+	Fixed       []ErrorTrend
+	New         int
 	LastUpdated string
+	Total       int
+	Listing     Listing
 }
 
-func ErroredNamespacesPage(w http.ResponseWriter, bucket string, wantJson bool, client *s3.Client) {
-	t := template.Must(template.ParseFiles("lib/templates/erroring_namespaces.html"))
+// ErroringNamespacesHistory is the erroring-namespaces-history report, which keeps the namespaces
+// failing in each apply-live run as the pipeline overwrites its report
+type ErroringNamespacesHistory = hoodaw.ErroringNamespacesHistory
+
+// ErrorTrend is how long a namespace has been failing with the link to its build and its team
+type ErrorTrend struct {
+	hoodaw.ErrorTrend
+	BuildURL string `json:"-"`
+	Team     string `json:"-"`
+}
 
-	byteValue, filestamp, err := utils.ImportS3File(client, bucket, "apply-live/gathered-namespaces-errors.json")
+// ErrorGroup is the namespaces failing with the same error signature
+type ErrorGroup struct {
+	Signature  string
+	Namespaces []NamespaceError
+}
+
+// NamespaceHistory is the error of a namespace in each run, newest first, nil for the runs
+// it applied in
+type NamespaceHistory struct {
+	Namespace string
+	Team      string
+	Slack     string
+	Runs      []*NamespaceError
+}
+
+// ErroredNamespacesPage lists the namespaces failing in the apply-live pipeline grouped by their
+// error, with the errors of each namespace in the previous runs, at most historyRuns of them. The
+// runs and trends come from the erroring-namespaces-history report, or when it hasn't run, the
+// versions of the apply-live report kept by the versioned bucket. The erroring namespaces are
+// filtered, sorted and paged by the query. The runs are only shown by the html page, so the data
+// formats leave them out, reading the trends only to filter or sort by when namespaces started
+// failing.
+func ErroredNamespacesPage(w http.ResponseWriter, bucket, hoodawBucket, buildURLTemplate string, historyRuns int, query Query, format Format, client *s3.Client) error {
+	var erroringNamespaces []NamespaceError
+	byteValue, filestamp, err := readReport(s3Load(client), bucket, erroringNamespacesKey, &erroringNamespaces)
 	if err != nil {
-		fmt.Println(err)
-		http.Error(w, "Failed to load data from S3", http.StatusInternalServerError)
-		return
+		return err
 	}
 
-	if wantJson {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(byteValue)
-		return
+	source := errorRunSource{load: s3Load(client), versions: s3Versions(client), loadVersion: s3LoadVersion(client), cache: erroringNamespacesVersions}
+	var runs [][]NamespaceError
+	var runTimes []string
+	var trends []hoodaw.ErrorTrend
+	switch {
+	case format == FormatHTML:
+		runs, runTimes, trends = source.errorRuns(bucket, hoodawBucket, historyRuns)
+		if len(runs) == 0 {
+			runs, runTimes = [][]NamespaceError{erroringNamespaces}, []string{filestamp}
+		}
+	case query.Sort == "failing_since" || query.Filters["failing_since"] != "":
+		trends = source.errorTrends(hoodawBucket)
 	}
 
-	var erroringNamespaces []NamespaceError
-	if err := json.Unmarshal(byteValue, &erroringNamespaces); err != nil {
-		fmt.Println(err)
-		http.Error(w, "Failed to parse JSON data", http.StatusInternalServerError)
-		return
+	// apply-live only applies the namespaces of the live cluster
+	owners := loadNamespaceOwners(client, hoodawBucket)
+	addDetails := func(errs []NamespaceError) {
+		for i, e := range errs {
+			owner := owners.lookup("live", e.Namespace)
+			errs[i].Team, errs[i].BusinessUnit, errs[i].Slack = owner.TeamName, owner.BusinessUnit, owner.SlackChannel
+			errs[i].BuildURL = buildURL(buildURLTemplate, e)
+		}
 	}
+	addDetails(erroringNamespaces)
+	for _, run := range runs {
+		addDetails(run)
+	}
+
+	sort.SliceStable(erroringNamespaces, func(i, j int) bool {
+		return erroringNamespaces[i].Namespace < erroringNamespaces[j].Namespace
+	})
 
 	data := ErroringNamespaces{
-		Namespaces:  erroringNamespaces,
+		History:     errorHistory(runs),
+		Runs:        runTimes,
+		Trends:      make(map[string]ErrorTrend),
 		LastUpdated: filestamp,
 	}
 
-	if err := t.ExecuteTemplate(w, "erroring_namespaces.html", data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	for _, trend := range trends {
+		t := ErrorTrend{ErrorTrend: trend, Team: owners.lookup("live", trend.Namespace).TeamName}
+		t.BuildURL = buildURL(buildURLTemplate, NamespaceError{NamespaceError: hoodaw.NamespaceError{Namespace: t.Namespace, BuildID: t.BuildID}})
+
+		switch t.Status {
+		case "fixed":
+			data.Fixed = append(data.Fixed, t)
+		case "new":
+			data.New++
+		}
+		data.Trends[t.Namespace] = t
+	}
+
+	matching, err := queryRows(query, erroringNamespaces, erroringNamespaceColumns(data.Trends))
+	if err != nil {
+		return err
 	}
+	data.Namespaces, data.Listing = paginate(query, matching)
+
+	if format != FormatHTML {
+		return writeRows(w, format, "erroring_namespaces", byteValue, "", data.Namespaces, data.Listing, erroringNamespacesCSV)
+	}
+
+	data.Groups = groupErrors(matching)
+	data.Total = len(matching)
+
+	return render(w, http.StatusOK, "erroring_namespaces.html", data)
+}
+
+// erroringNamespaceColumns are the columns the erroring namespaces can be filtered and sorted by,
+// with when each started failing from its trend
+func erroringNamespaceColumns(trends map[string]ErrorTrend) []column[NamespaceError] {
+	return []column[NamespaceError]{
+		{name: "namespace", value: func(e NamespaceError) string { return e.Namespace }},
+		{name: "team", value: func(e NamespaceError) string { return e.Team }},
+		{name: "business_unit", value: func(e NamespaceError) string { return e.BusinessUnit }},
+		{name: "slack_channel", value: func(e NamespaceError) string { return e.Slack }},
+		{name: "failing_since", value: func(e NamespaceError) string { return trends[e.Namespace].FirstSeen }},
+		{name: "error", value: func(e NamespaceError) string { return e.Error }},
+		{name: "build_id", value: func(e NamespaceError) string { return strconv.Itoa(e.BuildID) }},
+	}
+}
+
+// errorRunSource reads the apply-live runs from the erroring-namespaces-history report, or the
+// versions of the apply-live report when it hasn't run
+type errorRunSource struct {
+	load        loadFunc
+	versions    versionsFunc
+	loadVersion loadVersionFunc
+	cache       *errorRunCache
+}
+
+// errorRunCache is the namespaces failing in each version of the apply-live report, by version
+// id. A version never changes once written, so each is only read once.
+type errorRunCache struct {
+	mu   sync.Mutex
+	runs map[string][]NamespaceError
+}
+
+// erroringNamespacesVersions are the versions of the apply-live report read by the page so far
+var erroringNamespacesVersions = &errorRunCache{runs: make(map[string][]NamespaceError)}
+
+// errorRuns returns at most limit of the latest apply-live runs, newest first, with when they ran
+// and the trends of the namespaces when they come from the erroring-namespaces-history report
+func (s errorRunSource) errorRuns(bucket, hoodawBucket string, limit int) ([][]NamespaceError, []string, []hoodaw.ErrorTrend) {
+	runs := make([][]NamespaceError, 0)
+	runTimes := make([]string, 0)
+
+	var history ErroringNamespacesHistory
+	_, _, err := readReport(s.load, hoodawBucket, erroringNamespacesHistoryKey, &history)
+	if err == nil && len(history.Runs) > 0 {
+		for i, run := range history.Runs {
+			if i == limit {
+				break
+			}
+			runs = append(runs, namespaceErrors(run.Namespaces))
+			runTimes = append(runTimes, run.UpdatedAt)
+		}
+		return runs, runTimes, history.Trends
+	}
+	if err != nil && errorStatus(err) != http.StatusNotFound {
+		slog.Warn("showing history from versions of report", "report", erroringNamespacesHistoryKey, "error", err)
+	}
+
+	versions, err := s.versions(bucket, erroringNamespacesKey, limit)
+	if err != nil {
+		slog.Warn("showing page without history", "report", erroringNamespacesKey, "error", err)
+		return runs, runTimes, nil
+	}
+
+	listed := make(map[string]bool, len(versions))
+	for _, v := range versions {
+		listed[v.VersionID] = true
+
+		run, err := s.versionRun(bucket, v.VersionID)
+		if err != nil {
+			slog.Warn("skipping version of report", "report", erroringNamespacesKey, "version", v.VersionID, "error", err)
+			continue
+		}
+		runs = append(runs, run)
+		runTimes = append(runTimes, v.LastModified.Format("2006-01-02 15:04"))
+	}
+	s.cache.keep(listed)
+
+	return runs, runTimes, nil
+}
+
+// errorTrends returns the trends of the namespaces from the erroring-namespaces-history report,
+// or none when it can't be read
+func (s errorRunSource) errorTrends(hoodawBucket string) []hoodaw.ErrorTrend {
+	var history ErroringNamespacesHistory
+	if _, _, err := readReport(s.load, hoodawBucket, erroringNamespacesHistoryKey, &history); err != nil {
+		if errorStatus(err) != http.StatusNotFound {
+			slog.Warn("listing namespaces without trends", "report", erroringNamespacesHistoryKey, "error", err)
+		}
+		return nil
+	}
+
+	return history.Trends
+}
+
+// namespaceErrors are the errors of a run of the history, to add the details of each namespace to
+func namespaceErrors(errs []hoodaw.NamespaceError) []NamespaceError {
+	result := make([]NamespaceError, 0, len(errs))
+	for _, e := range errs {
+		result = append(result, NamespaceError{NamespaceError: e})
+	}
+	return result
+}
+
+// versionRun returns the namespaces failing in the version of the apply-live report, reading it
+// when it isn't cached. The run is a copy, as the page adds the details of each namespace to it.
+func (s errorRunSource) versionRun(bucket, versionID string) ([]NamespaceError, error) {
+	s.cache.mu.Lock()
+	run, ok := s.cache.runs[versionID]
+	s.cache.mu.Unlock()
+
+	if !ok {
+		data, err := s.loadVersion(bucket, erroringNamespacesKey, versionID)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &run); err != nil {
+			countFetchError(erroringNamespacesKey, err)
+			return nil, err
+		}
+
+		s.cache.mu.Lock()
+		s.cache.runs[versionID] = run
+		s.cache.mu.Unlock()
+	}
+
+	return append([]NamespaceError(nil), run...), nil
+}
+
+// keep drops the runs of the versions which are no longer listed, as the bucket has expired them
+func (c *errorRunCache) keep(versionIDs map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id := range c.runs {
+		if !versionIDs[id] {
+			delete(c.runs, id)
+		}
+	}
+}
+
+// buildURL returns the link to the CI build of the error from a template containing {build_id}
+// and {namespace}, or nothing when there's no template or build
+func buildURL(urlTemplate string, e NamespaceError) string {
+	if urlTemplate == "" || e.BuildID == 0 {
+		return ""
+	}
+
+	return strings.NewReplacer(
+		"{build_id}", strconv.Itoa(e.BuildID),
+		"{namespace}", e.Namespace,
+	).Replace(urlTemplate)
+}
+
+// signatureReplacements take the values which differ between namespaces failing for the same
+// reason out of their errors, most specific first
+var signatureReplacements = []struct {
+	re   *regexp.Regexp
+	with string
+}{
+	{regexp.MustCompile(`\x1b\[[0-9;]*m`), ""},
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<time>"},
+	{regexp.MustCompile(`arn:aws[a-z-]*:[^\s"',]+`), "<arn>"},
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<id>"},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}\b`), "<ip>"},
+	{regexp.MustCompile(`"[^"\n]*"`), `"<value>"`},
+	{regexp.MustCompile(`\b[0-9a-f]*[0-9][0-9a-f]*\b`), "<n>"},
+	{regexp.MustCompile(`\s+`), " "},
+}
+
+// errorSignature normalises an error so the same failure in different namespaces has the same
+// signature, e.g. taking out the namespace name, resource names, ids and times
+func errorSignature(e NamespaceError) string {
+	signature := replaceToken(e.Error, e.Namespace, "<namespace>")
+
+	for _, r := range signatureReplacements {
+		signature = r.re.ReplaceAllString(signature, r.with)
+	}
+
+	signature = strings.TrimSpace(signature)
+	if len(signature) > 300 {
+		signature = signature[:300] + "..."
+	}
+
+	return signature
+}
+
+// replaceToken replaces each token in s with, where a token isn't part of a longer name, i.e. it
+// isn't next to a letter or digit
+func replaceToken(s, token, with string) string {
+	if token == "" {
+		return s
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, token)
+		if i < 0 {
+			break
+		}
+		end := i + len(token)
+		if (i == 0 || !isAlphanumeric(s[i-1])) && (end == len(s) || !isAlphanumeric(s[end])) {
+			b.WriteString(s[:i])
+			b.WriteString(with)
+		} else {
+			b.WriteString(s[:end])
+		}
+		s = s[end:]
+	}
+	b.WriteString(s)
+
+	return b.String()
+}
+
+func isAlphanumeric(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// groupErrors groups the errors by signature, the most common first
+func groupErrors(errors []NamespaceError) []ErrorGroup {
+	bySignature := make(map[string][]NamespaceError)
+	for _, e := range errors {
+		signature := errorSignature(e)
+		bySignature[signature] = append(bySignature[signature], e)
+	}
+
+	groups := make([]ErrorGroup, 0, len(bySignature))
+	for signature, namespaces := range bySignature {
+		groups = append(groups, ErrorGroup{Signature: signature, Namespaces: namespaces})
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].Namespaces) != len(groups[j].Namespaces) {
+			return len(groups[i].Namespaces) > len(groups[j].Namespaces)
+		}
+		return groups[i].Signature < groups[j].Signature
+	})
+
+	return groups
+}
+
+// errorHistory returns the error of each namespace which failed in any of the runs, newest run
+// first. The namespaces failing in the latest run go first.
+func errorHistory(runs [][]NamespaceError) []NamespaceHistory {
+	byNamespace := make(map[string]*NamespaceHistory)
+
+	for i, run := range runs {
+		for j := range run {
+			e := &run[j]
+
+			h, ok := byNamespace[e.Namespace]
+			if !ok {
+				h = &NamespaceHistory{Namespace: e.Namespace, Team: e.Team, Slack: e.Slack, Runs: make([]*NamespaceError, len(runs))}
+				byNamespace[e.Namespace] = h
+			}
+			h.Runs[i] = e
+		}
+	}
+
+	history := make([]NamespaceHistory, 0, len(byNamespace))
+	for _, h := range byNamespace {
+		history = append(history, *h)
+	}
+
+	sort.Slice(history, func(i, j int) bool {
+		a, b := history[i].Runs[0] != nil, history[j].Runs[0] != nil
+		if a != b {
+			return a
+		}
+		return history[i].Namespace < history[j].Namespace
+	})
+
+	return history
+}
+
+// erroringNamespacesCSV is a row for each namespace which failed to apply
+func erroringNamespacesCSV(data []byte) (csvTable, error) {
+	var erroringNamespaces []NamespaceError
+	if err := json.Unmarshal(data, &erroringNamespaces); err != nil {
+		return csvTable{}, err
+	}
+
+	table := csvTable{header: []string{"namespace", "build_id", "error"}}
+	for _, e := range erroringNamespaces {
+		table.rows = append(table.rows, []string{e.Namespace, strconv.Itoa(e.BuildID), e.Error})
+	}
+
+	return table, nil
 }
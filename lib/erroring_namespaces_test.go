@@ -0,0 +1,178 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+)
+
+func Test_errorSignature(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b NamespaceError
+		same bool
+	}{
+		{
+			name: "namespace, resource names and ids",
+			a:    NamespaceError{NamespaceError: hoodaw.NamespaceError{Namespace: "app-dev", Error: `Error: creating IAM Role "cloud-platform-app-dev-12ab": EntityAlreadyExists: arn:aws:iam::123456789012:role/app-dev`}},
+			b:    NamespaceError{NamespaceError: hoodaw.NamespaceError{Namespace: "api-prod", Error: `Error: creating IAM Role "cloud-platform-api-prod-9f3c": EntityAlreadyExists: arn:aws:iam::123456789012:role/api-prod`}},
+			same: true,
+		},
+		{
+			name: "times and whitespace",
+			a:    NamespaceError{NamespaceError: hoodaw.NamespaceError{Namespace: "a", Error: "Error: timeout at 2025-03-01T10:00:00Z\n  waiting for rollout"}},
+			b:    NamespaceError{NamespaceError: hoodaw.NamespaceError{Namespace: "b", Error: "Error: timeout at 2025-03-02 11:30:00   waiting for rollout"}},
+			same: true,
+		},
+		{
+			name: "different errors",
+			a:    NamespaceError{NamespaceError: hoodaw.NamespaceError{Namespace: "a", Error: "Error: Unauthorized"}},
+			b:    NamespaceError{NamespaceError: hoodaw.NamespaceError{Namespace: "b", Error: "Error: context deadline exceeded"}},
+			same: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := errorSignature(tt.a), errorSignature(tt.b)
+			if (a == b) != tt.same {
+				t.Errorf("errorSignature() = %q and %q, want same %v", a, b, tt.same)
+			}
+		})
+	}
+}
+
+func Test_groupErrors(t *testing.T) {
+	errs := []NamespaceError{
+		{NamespaceError: hoodaw.NamespaceError{Namespace: "a", Error: "Error: Unauthorized"}},
+		{NamespaceError: hoodaw.NamespaceError{Namespace: "b", Error: `Error: namespace "b" not found`}},
+		{NamespaceError: hoodaw.NamespaceError{Namespace: "c", Error: `Error: namespace "c" not found`}},
+	}
+
+	want := []ErrorGroup{
+		{Signature: `Error: namespace "<value>" not found`, Namespaces: []NamespaceError{errs[1], errs[2]}},
+		{Signature: "Error: Unauthorized", Namespaces: []NamespaceError{errs[0]}},
+	}
+
+	if got := groupErrors(errs); !reflect.DeepEqual(got, want) {
+		t.Errorf("groupErrors() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_buildURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		e        NamespaceError
+		want     string
+	}{
+		{"build id", "https://concourse.example.com/builds/{build_id}", NamespaceError{NamespaceError: hoodaw.NamespaceError{Namespace: "a", BuildID: 42}}, "https://concourse.example.com/builds/42"},
+		{"namespace", "https://ci.example.com/{namespace}/{build_id}", NamespaceError{NamespaceError: hoodaw.NamespaceError{Namespace: "a", BuildID: 42}}, "https://ci.example.com/a/42"},
+		{"no template", "", NamespaceError{NamespaceError: hoodaw.NamespaceError{Namespace: "a", BuildID: 42}}, ""},
+		{"no build", "https://concourse.example.com/builds/{build_id}", NamespaceError{NamespaceError: hoodaw.NamespaceError{Namespace: "a"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildURL(tt.template, tt.e); got != tt.want {
+				t.Errorf("buildURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_errorHistory(t *testing.T) {
+	runs := [][]NamespaceError{
+		{{NamespaceError: hoodaw.NamespaceError{Namespace: "b", Error: "b failed", BuildID: 3}}},
+		{{NamespaceError: hoodaw.NamespaceError{Namespace: "a", Error: "a failed", BuildID: 2}}, {NamespaceError: hoodaw.NamespaceError{Namespace: "b", Error: "b failed", BuildID: 2}}},
+		{{NamespaceError: hoodaw.NamespaceError{Namespace: "a", Error: "a failed", BuildID: 1}}},
+	}
+
+	want := []NamespaceHistory{
+		{Namespace: "b", Runs: []*NamespaceError{&runs[0][0], &runs[1][1], nil}},
+		{Namespace: "a", Runs: []*NamespaceError{nil, &runs[1][0], &runs[2][0]}},
+	}
+
+	if got := errorHistory(runs); !reflect.DeepEqual(got, want) {
+		t.Errorf("errorHistory() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_replaceToken(t *testing.T) {
+	tests := []struct {
+		name, s, token, want string
+	}{
+		{"whole string", "app", "app", "<namespace>"},
+		{"between punctuation", `namespace "app" in app/role`, "app", `namespace "<namespace>" in <namespace>/role`},
+		{"part of a longer name", "apps app-dev myapp app", "app", "apps <namespace>-dev myapp <namespace>"},
+		{"no token", "app", "", "app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceToken(tt.s, tt.token, "<namespace>"); got != tt.want {
+				t.Errorf("replaceToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_errorRunSource_errorRuns(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	listed := []utils.S3FileVersion{{VersionID: "v2", LastModified: now}, {VersionID: "v1", LastModified: now.Add(-time.Hour)}}
+	reads := make(map[string]int)
+	source := errorRunSource{
+		load: fakeReports{}.load,
+		versions: func(bucket, key string, limit int) ([]utils.S3FileVersion, error) {
+			return listed, nil
+		},
+		loadVersion: func(bucket, key, versionID string) ([]byte, error) {
+			reads[versionID]++
+			return []byte(`[{"namespace": "ns-` + versionID + `"}]`), nil
+		},
+		cache: &errorRunCache{runs: make(map[string][]NamespaceError)},
+	}
+
+	runs, runTimes, _ := source.errorRuns("apply-live", "hoodaw", 10)
+	if len(runs) != 2 || runs[0][0].Namespace != "ns-v2" || runTimes[1] != "2025-03-01 09:00" {
+		t.Fatalf("errorRuns() = %v at %v, want a run from each version, newest first", runs, runTimes)
+	}
+	runs[0][0].Team = "changed by the page"
+
+	listed = listed[:1]
+	runs, _, _ = source.errorRuns("apply-live", "hoodaw", 10)
+	if want := map[string]int{"v1": 1, "v2": 1}; !reflect.DeepEqual(reads, want) {
+		t.Errorf("errorRuns() read versions %v, want each read once", reads)
+	}
+	if runs[0][0].Team != "" {
+		t.Error("errorRuns() returned the run changed by an earlier page")
+	}
+	if _, ok := source.cache.runs["v1"]; ok {
+		t.Error("errorRuns() kept the run of a version no longer listed")
+	}
+
+	source.load = fakeReports{"hoodaw/" + erroringNamespacesHistoryKey: {`{"runs": [{"updated_at": "2025-03-01 10:00", "namespaces": []}]}`, "1"}}.load
+	if _, runTimes, _ := source.errorRuns("apply-live", "hoodaw", 10); !reflect.DeepEqual(runTimes, []string{"2025-03-01 10:00"}) {
+		t.Errorf("errorRuns() = runs at %v, want the runs of the history report", runTimes)
+	}
+}
+
+func Test_errorRunSource_errorTrends(t *testing.T) {
+	source := errorRunSource{
+		load: fakeReports{"hoodaw/" + erroringNamespacesHistoryKey: {`{"runs": [{"updated_at": "2025-03-01 10:00", "namespaces": [{"namespace": "ns-one"}]}], "trends": [{"Namespace": "ns-one", "Status": "new", "FirstSeen": "2025-03-01 10:00"}]}`, "1"}}.load,
+		versions: func(bucket, key string, limit int) ([]utils.S3FileVersion, error) {
+			t.Error("errorTrends() listed the versions of the apply-live report")
+			return nil, nil
+		},
+	}
+
+	want := []hoodaw.ErrorTrend{{Namespace: "ns-one", Status: "new", FirstSeen: "2025-03-01 10:00"}}
+	if got := source.errorTrends("hoodaw"); !reflect.DeepEqual(got, want) {
+		t.Errorf("errorTrends() = %+v, want %+v", got, want)
+	}
+
+	source.load = fakeReports{}.load
+	if got := source.errorTrends("hoodaw"); got != nil {
+		t.Errorf("errorTrends() without the history = %+v, want none", got)
+	}
+}
@@ -0,0 +1,151 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/aws/smithy-go"
+)
+
+// StatusError is an error of a page with the status it is returned with
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// ReportError is a failure to read or parse the json of the report with the key
+type ReportError struct {
+	Key string
+	Err error
+}
+
+func (e *ReportError) Error() string {
+	return fmt.Sprintf("unable to read %s: %s", e.Key, e.Err)
+}
+
+func (e *ReportError) Unwrap() error {
+	return e.Err
+}
+
+// message describes the failure without the details of the store, which are only logged
+func (e *ReportError) message(status int) string {
+	switch status {
+	case http.StatusNotFound:
+		return fmt.Sprintf("The %s report hasn't been written yet", e.Key)
+	case http.StatusBadGateway:
+		return fmt.Sprintf("Not allowed to read the %s report", e.Key)
+	case http.StatusGatewayTimeout:
+		return fmt.Sprintf("Timed out reading the %s report", e.Key)
+	}
+	if isParseError(e.Err) {
+		return fmt.Sprintf("The %s report isn't valid json", e.Key)
+	}
+
+	return fmt.Sprintf("Unable to read the %s report", e.Key)
+}
+
+// readReport reads the json of the report with the key into v, returning the json and when it was
+// written
+func readReport(load loadFunc, bucket, key string, v any) ([]byte, string, error) {
+	byteValue, filestamp, err := load(bucket, key)
+	if err != nil {
+		return nil, "", &ReportError{Key: key, Err: err}
+	}
+
+	if err := json.Unmarshal(byteValue, v); err != nil {
+		return nil, "", &ReportError{Key: key, Err: err}
+	}
+
+	return byteValue, filestamp, nil
+}
+
+// errorStatus is the status a page fails with: a report which doesn't exist is not found, one the
+// store won't let us read is a bad gateway, a timeout of the store is a gateway timeout and
+// anything else, including a report which isn't valid json, is an internal server error
+func errorStatus(err error) int {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Status
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NoSuchKey", "NoSuchBucket", "NotFound":
+			return http.StatusNotFound
+		case "AccessDenied", "Forbidden", "InvalidAccessKeyId", "SignatureDoesNotMatch", "ExpiredToken":
+			return http.StatusBadGateway
+		case "RequestTimeout":
+			return http.StatusGatewayTimeout
+		}
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, fs.ErrPermission) {
+		return http.StatusBadGateway
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusInternalServerError
+}
+
+func isParseError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
+
+// ErrorBody is what a page which failed returns, as json for the data formats
+type ErrorBody struct {
+	Status  int    `json:"status"`
+	Error   string `json:"error"`
+	Message string `json:"message"`
+	Report  string `json:"report,omitempty"`
+}
+
+// WriteError writes the error a page failed with, as an error page for html and as json for the
+// other formats, logging it with the report it failed to read
+func WriteError(w http.ResponseWriter, r *http.Request, format Format, err error) {
+	status := errorStatus(err)
+	body := ErrorBody{Status: status, Error: http.StatusText(status), Message: err.Error()}
+
+	var re *ReportError
+	if errors.As(err, &re) {
+		body.Report, body.Message = re.Key, re.message(status)
+	}
+
+	level := slog.LevelWarn
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	slog.Log(r.Context(), level, "request failed", "method", r.Method, "path", r.URL.Path, "status", status, "report", body.Report, "error", err)
+
+	if format == FormatHTML && render(w, status, "error.html", body) == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,75 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+func Test_errorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"bad request", &StatusError{Status: http.StatusBadRequest, Err: errors.New("unknown sort")}, http.StatusBadRequest},
+		{"no such key", &ReportError{Key: "hosted_services.json", Err: &types.NoSuchKey{}}, http.StatusNotFound},
+		{"no such bucket", &ReportError{Key: "hosted_services.json", Err: fmt.Errorf("get object: %w", &types.NoSuchBucket{})}, http.StatusNotFound},
+		{"missing file", &ReportError{Key: "hosted_services.json", Err: fs.ErrNotExist}, http.StatusNotFound},
+		{"access denied", &ReportError{Key: "hosted_services.json", Err: &smithy.GenericAPIError{Code: "AccessDenied"}}, http.StatusBadGateway},
+		{"timeout", &ReportError{Key: "hosted_services.json", Err: context.DeadlineExceeded}, http.StatusGatewayTimeout},
+		{"invalid json", &ReportError{Key: "hosted_services.json", Err: json.Unmarshal([]byte("{"), &HostedServices{})}, http.StatusInternalServerError},
+		{"anything else", errors.New("template: no such template"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorStatus(tt.err); got != tt.want {
+				t.Errorf("errorStatus() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readReport(t *testing.T) {
+	reports := fakeReports{
+		"hoodaw/hosted_services.json":      {`{"namespace_details": [{"Name": "ns-one"}]}`, "1"},
+		"hoodaw/namespace_compliance.json": {`{"namespace_compliance": `, "1"},
+	}
+
+	var hostedServices HostedServices
+	if _, stamp, err := readReport(reports.load, "hoodaw", "hosted_services.json", &hostedServices); err != nil || stamp != "1" || len(hostedServices.HostedServices) != 1 {
+		t.Errorf("readReport() = %+v, %s, %v, want the hosted services", hostedServices, stamp, err)
+	}
+
+	for _, key := range []string{"namespace_compliance.json", "live_one_domains.json"} {
+		var v any
+		_, _, err := readReport(reports.load, "hoodaw", key, &v)
+		var re *ReportError
+		if !errors.As(err, &re) || re.Key != key {
+			t.Errorf("readReport(%s) error = %v, want a report error with the key", key, err)
+		}
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	err := &ReportError{Key: "live_one_domains.json", Err: &smithy.GenericAPIError{Code: "AccessDenied", Message: "request id 1234"}}
+
+	w := httptest.NewRecorder()
+	WriteError(w, httptest.NewRequest(http.MethodGet, "/live_one_domains?format=json", nil), FormatJSON, err)
+
+	if w.Code != http.StatusBadGateway || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("WriteError() = %d %s, want a 502 of json", w.Code, w.Header().Get("Content-Type"))
+	}
+	want := `{"status":502,"error":"Bad Gateway","message":"Not allowed to read the live_one_domains.json report","report":"live_one_domains.json"}` + "\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("WriteError() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,37 @@
+package lib
+
+import "sort"
+
+// filterOptions returns the distinct, sorted values of a field of a report for the drop down
+// filters of a page, e.g. the clusters. Empty values, like results from before a report was
+// tagged by cluster, are left out.
+func filterOptions(values []string) []string {
+	seen := make(map[string]bool)
+	options := make([]string, 0)
+
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			options = append(options, v)
+		}
+	}
+
+	sort.Strings(options)
+	return options
+}
+
+// matchesFilter reports whether a result with the values is shown when a page is filtered
+// to filter, an empty filter shows all results
+func matchesFilter(filter string, values ...string) bool {
+	if filter == "" {
+		return true
+	}
+
+	for _, v := range values {
+		if v == filter {
+			return true
+		}
+	}
+
+	return false
+}
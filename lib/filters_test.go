@@ -0,0 +1,36 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_filterOptions(t *testing.T) {
+	got := filterOptions([]string{"manager", "live", "", "live"})
+	want := []string{"live", "manager"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("filterOptions() = %v, want %v", got, want)
+	}
+}
+
+func Test_matchesFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		values []string
+		want   bool
+	}{
+		{"no filter", "", []string{"live"}, true},
+		{"matching cluster", "manager", []string{"live", "manager"}, true},
+		{"other cluster", "manager", []string{"live"}, false},
+		{"untagged result", "live", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesFilter(tt.filter, tt.values...); got != tt.want {
+				t.Errorf("matchesFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is how a page is written, the html page or the data of its report
+type Format string
+
+const (
+	FormatHTML Format = "html"
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatYAML Format = "yaml"
+)
+
+// mediaTypes are the formats of the media types which can be asked for in an Accept header
+var mediaTypes = map[string]Format{
+	"text/html":             FormatHTML,
+	"application/xhtml+xml": FormatHTML,
+	"text/*":                FormatHTML,
+	"*/*":                   FormatHTML,
+	"application/json":      FormatJSON,
+	"application/*":         FormatJSON,
+	"text/csv":              FormatCSV,
+	"application/yaml":      FormatYAML,
+	"application/x-yaml":    FormatYAML,
+	"text/yaml":             FormatYAML,
+}
+
+// NegotiateFormat returns the format a page is asked for in, from the format query parameter or
+// else the media type of the Accept header with the highest quality, the first of them when
+// several have the same quality. Pages are html when nothing else is asked for.
+func NegotiateFormat(r *http.Request) (Format, error) {
+	if f := r.URL.Query().Get("format"); f != "" {
+		switch format := Format(strings.ToLower(f)); format {
+		case FormatHTML, FormatJSON, FormatCSV, FormatYAML:
+			return format, nil
+		}
+		return "", fmt.Errorf("unknown format %q, use html, json, csv or yaml", f)
+	}
+
+	best, bestQuality := FormatHTML, 0.0
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+
+		format, ok := mediaTypes[mediaType]
+		if !ok {
+			continue
+		}
+
+		quality := 1.0
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			quality = q
+		}
+
+		if quality > bestQuality {
+			best, bestQuality = format, quality
+		}
+	}
+
+	return best, nil
+}
+
+// csvTable is the data of a report flattened into rows for a spreadsheet
+type csvTable struct {
+	header []string
+	rows   [][]string
+}
+
+// writeReport writes the json data of the report called name in a data format, with toCSV
+// flattening it into rows
+func writeReport(w http.ResponseWriter, format Format, name string, data []byte, toCSV func([]byte) (csvTable, error)) error {
+	switch format {
+	case FormatYAML:
+		out, err := jsonToYAML(data)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s to yaml: %w", name, err)
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(out)
+	case FormatCSV:
+		table, err := toCSV(data)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s to csv: %w", name, err)
+		}
+		out, err := table.encode()
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".csv"))
+		w.Write(out)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+
+	return nil
+}
+
+// jsonToYAML converts json to yaml, with the keys of each object sorted
+func jsonToYAML(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(v)
+}
+
+// encode writes the header and rows as csv, in the order of the rows of the report
+func (t csvTable) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	cw.Write(t.header)
+	cw.WriteAll(t.rows)
+
+	return buf.Bytes(), cw.Error()
+}
+
+// joinCSV joins a list into a single csv cell
+func joinCSV(values []string) string {
+	return strings.Join(values, " ")
+}
+
+// sortedKeys returns the keys of a map of a report in order, so it is written the same way each time
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
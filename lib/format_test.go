@@ -0,0 +1,104 @@
+package lib
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		accept  string
+		want    Format
+		wantErr bool
+	}{
+		{"nothing asked for", "/", "", FormatHTML, false},
+		{"browser", "/", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", FormatHTML, false},
+		{"json", "/", "application/json", FormatJSON, false},
+		{"json with other types", "/", "application/json, text/plain", FormatJSON, false},
+		{"highest quality", "/", "text/html;q=0.5, text/csv", FormatCSV, false},
+		{"first of the same quality", "/", "application/yaml, application/json", FormatYAML, false},
+		{"unknown types", "/", "image/png, text/plain", FormatHTML, false},
+		{"not acceptable", "/", "application/json;q=0", FormatHTML, false},
+		{"format overrides accept", "/?format=CSV", "application/json", FormatCSV, false},
+		{"unknown format", "/?format=xml", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.url, nil)
+			r.Header.Set("Accept", tt.accept)
+
+			got, err := NegotiateFormat(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NegotiateFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NegotiateFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeReport(t *testing.T) {
+	data := []byte(`{"namespace": [{"namespace": "ns2", "breakdown": {"EC2": 1.5}, "total": 1.5, "clusters": ["live"]}, {"namespace": "ns1", "breakdown": {"S3": 0.25, "RDS": 2}, "total": 2.25, "clusters": ["live", "live-2"]}]}`)
+
+	tests := []struct {
+		name            string
+		format          Format
+		wantContentType string
+		wantBody        string
+	}{
+		{"json", FormatJSON, "application/json", string(data)},
+		{
+			"csv in the order of the rows",
+			FormatCSV,
+			"text/csv",
+			"namespace,clusters,resource,cost\n" +
+				"ns2,live,EC2,1.5\n" +
+				"ns2,live,total,1.5\n" +
+				"ns1,live live-2,RDS,2\n" +
+				"ns1,live live-2,S3,0.25\n" +
+				"ns1,live live-2,total,2.25\n",
+		},
+		{
+			"yaml",
+			FormatYAML,
+			"application/yaml",
+			"namespace:\n" +
+				"    - breakdown:\n" +
+				"        EC2: 1.5\n" +
+				"      clusters:\n" +
+				"        - live\n" +
+				"      namespace: ns2\n" +
+				"      total: 1.5\n" +
+				"    - breakdown:\n" +
+				"        RDS: 2\n" +
+				"        S3: 0.25\n" +
+				"      clusters:\n" +
+				"        - live\n" +
+				"        - live-2\n" +
+				"      namespace: ns1\n" +
+				"      total: 2.25\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			if err := writeReport(w, tt.format, "namespace_costs", data, namespaceCostsCSV); err != nil {
+				t.Fatal(err)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+
+	if err := writeReport(httptest.NewRecorder(), FormatCSV, "namespace_costs", []byte("not json"), namespaceCostsCSV); err == nil {
+		t.Error("writeReport() of invalid json as csv didn't return an error")
+	}
+}
@@ -0,0 +1,153 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"gopkg.in/yaml.v3"
+)
+
+// FreshnessConfig is how many hours after each report was written it is stale, from
+// config/report_freshness.yaml. The ruby reports are checked by the ruby dashboard.
+type FreshnessConfig struct {
+	DefaultMaxAgeHours int            `yaml:"default_max_age_hours"`
+	Go                 map[string]int `yaml:"go"`
+	Ruby               map[string]int `yaml:"ruby"`
+}
+
+// LoadFreshnessConfig reads the maximum ages of the reports from the yaml file at path
+func LoadFreshnessConfig(path string) (FreshnessConfig, error) {
+	var config FreshnessConfig
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("unable to read the report freshness config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("unable to parse the report freshness config %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// maxAge is how long after the report with the key was written it is stale
+func (c FreshnessConfig) maxAge(key string) time.Duration {
+	hours, ok := c.Go[key]
+	if !ok {
+		hours = c.DefaultMaxAgeHours
+	}
+
+	return time.Duration(hours) * time.Hour
+}
+
+// Freshness is the age of every report, and how many of them need their cronjob looking at
+type Freshness struct {
+	Reports   []ReportStatus `json:"reports"`
+	Stale     int            `json:"stale"`
+	UpdatedAt string         `json:"updated_at"`
+	Total     int            `json:"-"`
+	Listing   Listing        `json:"-"`
+}
+
+// FreshnessChecker checks when each report was last written against its maximum age
+type FreshnessChecker struct {
+	stat    statFunc
+	reports map[string]reportObject
+	config  FreshnessConfig
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// NewFreshnessChecker returns the check of every report written by the cronjobs to the hoodaw
+// bucket, and of the erroring namespaces in the apply-live bucket
+func NewFreshnessChecker(client *s3.Client, bucket, errorNsBucket string, config FreshnessConfig) *FreshnessChecker {
+	return &FreshnessChecker{
+		stat:    s3Stat(client),
+		reports: freshnessReports(bucket, errorNsBucket),
+		config:  config,
+		timeout: 10 * time.Second,
+		now:     time.Now,
+	}
+}
+
+// freshnessReports are the reports of the pages, along with those only read by other reports
+func freshnessReports(bucket, errorNsBucket string) map[string]reportObject {
+	reports := pageReports(bucket, errorNsBucket)
+	reports["domain_reachability"] = reportObject{bucket, "domain_reachability.json"}
+	reports["erroring_namespaces_history"] = reportObject{bucket, "erroring_namespaces_history.json"}
+
+	return reports
+}
+
+// Check returns the status of each report. A report which is stale, hasn't been written or can't
+// be read is counted as stale, as each means its cronjob isn't writing it.
+func (fc *FreshnessChecker) Check(ctx context.Context) Freshness {
+	ctx, cancel := context.WithTimeout(ctx, fc.timeout)
+	defer cancel()
+
+	now := fc.now()
+	freshness := Freshness{
+		Reports:   checkReports(ctx, fc.stat, now, fc.reports, func(r reportObject) time.Duration { return fc.config.maxAge(r.key) }),
+		UpdatedAt: now.UTC().Format(time.RFC3339),
+	}
+	for _, r := range freshness.Reports {
+		if r.Status != ReportReady {
+			freshness.Stale++
+		}
+	}
+
+	return freshness
+}
+
+// reportStatusColumns are the columns the reports can be filtered and sorted by
+var reportStatusColumns = []column[ReportStatus]{
+	{name: "report", value: func(r ReportStatus) string { return r.Report }},
+	{name: "bucket", value: func(r ReportStatus) string { return r.Bucket }},
+	{name: "status", value: func(r ReportStatus) string { return r.Status }},
+	{name: "last_modified", value: func(r ReportStatus) string { return r.LastModified }},
+	{name: "age", value: func(r ReportStatus) string { return fmt.Sprint(r.AgeHours) }},
+}
+
+// FreshnessPage lists how long ago each report was written and whether it is stale, filtered,
+// sorted and paged by the query
+func FreshnessPage(w http.ResponseWriter, r *http.Request, query Query, format Format, checker *FreshnessChecker) error {
+	freshness := checker.Check(r.Context())
+
+	matching, err := queryRows(query, freshness.Reports, reportStatusColumns)
+	if err != nil {
+		return err
+	}
+	freshness.Reports, freshness.Listing = paginate(query, matching)
+
+	if format != FormatHTML {
+		out, err := json.Marshal(freshness)
+		if err != nil {
+			return err
+		}
+		return writeRows(w, format, "freshness", out, "reports", freshness.Reports, freshness.Listing, freshnessCSV)
+	}
+
+	freshness.Total = len(matching)
+
+	return render(w, http.StatusOK, "freshness.html", freshness)
+}
+
+// freshnessCSV is a row for each report
+func freshnessCSV(data []byte) (csvTable, error) {
+	var freshness Freshness
+	if err := json.Unmarshal(data, &freshness); err != nil {
+		return csvTable{}, err
+	}
+
+	table := csvTable{header: []string{"report", "bucket", "status", "last_modified", "age_hours", "max_age", "error"}}
+	for _, r := range freshness.Reports {
+		table.rows = append(table.rows, []string{r.Report, r.Bucket, r.Status, r.LastModified, fmt.Sprint(r.AgeHours), r.MaxAge, r.Error})
+	}
+
+	return table, nil
+}
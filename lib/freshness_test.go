@@ -0,0 +1,118 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestLoadFreshnessConfig(t *testing.T) {
+	config, err := LoadFreshnessConfig("../config/report_freshness.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every report the go web application checks has a maximum age of its own
+	for _, r := range freshnessReports("hoodaw", "apply-live") {
+		if _, ok := config.Go[r.key]; !ok {
+			t.Errorf("config has no maximum age for %s", r.key)
+		}
+	}
+	if config.DefaultMaxAgeHours == 0 || len(config.Ruby) == 0 {
+		t.Errorf("config = %+v, want a default and the ruby reports", config)
+	}
+
+	if _, err := LoadFreshnessConfig("nothing.yaml"); err == nil {
+		t.Error("LoadFreshnessConfig() of a missing file didn't return an error")
+	}
+}
+
+func Test_FreshnessChecker_Check(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	written := map[string]time.Time{
+		"hosted_services.json":            now.Add(-2 * time.Hour),
+		"namespace_usage.json":            now.Add(-13 * time.Hour),
+		"infrastructure_deployments.json": now.Add(-20 * 24 * time.Hour),
+	}
+
+	fc := &FreshnessChecker{
+		stat: func(ctx context.Context, bucket, key string) (time.Time, error) {
+			if w, ok := written[key]; ok {
+				return w, nil
+			}
+			return time.Time{}, &types.NoSuchKey{}
+		},
+		reports: map[string]reportObject{
+			"hosted_services":            {"hoodaw", "hosted_services.json"},
+			"namespace_usage":            {"hoodaw", "namespace_usage.json"},
+			"infrastructure_deployments": {"hoodaw", "infrastructure_deployments.json"},
+			"certificate_expiry":         {"hoodaw", "certificate_expiry.json"},
+		},
+		config: FreshnessConfig{
+			DefaultMaxAgeHours: 26,
+			Go:                 map[string]int{"namespace_usage.json": 12, "infrastructure_deployments.json": 768},
+		},
+		timeout: time.Second,
+		now:     func() time.Time { return now },
+	}
+
+	freshness := fc.Check(context.Background())
+
+	want := map[string]string{
+		"hosted_services.json":            ReportReady,
+		"namespace_usage.json":            ReportStale,
+		"infrastructure_deployments.json": ReportReady,
+		"certificate_expiry.json":         ReportMissing,
+	}
+	for _, r := range freshness.Reports {
+		if r.Status != want[r.Report] {
+			t.Errorf("Check() %s status = %s, want %s", r.Report, r.Status, want[r.Report])
+		}
+	}
+	if freshness.Stale != 2 {
+		t.Errorf("Check() stale = %d, want 2", freshness.Stale)
+	}
+	if freshness.Reports[0].Report != "certificate_expiry.json" {
+		t.Errorf("Check() reports aren't sorted by name, first is %s", freshness.Reports[0].Report)
+	}
+}
+
+func TestFreshnessPage(t *testing.T) {
+	now := time.Now()
+	fc := &FreshnessChecker{
+		stat: func(ctx context.Context, bucket, key string) (time.Time, error) {
+			if key == "hosted_services.json" {
+				return now.Add(-48 * time.Hour), nil
+			}
+			return now, nil
+		},
+		reports: freshnessReports("hoodaw", "apply-live"),
+		config:  FreshnessConfig{DefaultMaxAgeHours: 26},
+		timeout: time.Second,
+		now:     func() time.Time { return now },
+	}
+
+	query, err := ParseQuery(url.Values{"status": {"stale"}}, FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	if err := FreshnessPage(w, httptest.NewRequest(http.MethodGet, "/freshness", nil), query, FormatJSON, fc); err != nil {
+		t.Fatal(err)
+	}
+
+	var got Freshness
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Stale != 1 || len(got.Reports) != 1 || got.Reports[0].Report != "hosted_services.json" || got.Reports[0].AgeHours != 48 {
+		t.Errorf("FreshnessPage() = %+v, want only the stale hosted_services.json", got)
+	}
+}
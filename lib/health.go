@@ -0,0 +1,157 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// The status of a report when checking it is fresh, or the server is ready
+const (
+	ReportReady      = "ok"
+	ReportMissing    = "missing"
+	ReportStale      = "stale"
+	ReportUnreadable = "unreadable"
+)
+
+// BuildInfo is the version of the server, set when the binary is built
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+	StartedAt string `json:"started_at"`
+}
+
+// ReportStatus is whether a report can be read, and when it was written
+type ReportStatus struct {
+	Report       string  `json:"report"`
+	Bucket       string  `json:"bucket"`
+	Status       string  `json:"status"`
+	LastModified string  `json:"last_modified,omitempty"`
+	Age          string  `json:"age,omitempty"`
+	AgeHours     float64 `json:"age_hours,omitempty"`
+	MaxAge       string  `json:"max_age,omitempty"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// Readiness checks the reports of the pages can be read from the store
+type Readiness struct {
+	stat    statFunc
+	reports map[string]reportObject
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// NewReadiness returns the check of the reports of the pages in the hoodaw and apply-live buckets
+func NewReadiness(client *s3.Client, bucket, errorNsBucket string) *Readiness {
+	return &Readiness{
+		stat:    s3Stat(client),
+		reports: pageReports(bucket, errorNsBucket),
+		timeout: 5 * time.Second,
+		now:     time.Now,
+	}
+}
+
+// Check returns the status of each report, by name, and whether the server is ready. It isn't when
+// a report can't be read. A report which hasn't been written yet is missing, which only leaves its
+// page empty, and how old each report is doesn't matter: restarting the server won't make a stale
+// report fresh, so staleness is shown by /freshness and /metrics instead.
+func (rd *Readiness) Check(ctx context.Context) ([]ReportStatus, bool) {
+	ctx, cancel := context.WithTimeout(ctx, rd.timeout)
+	defer cancel()
+
+	statuses := checkReports(ctx, rd.stat, rd.now(), rd.reports, func(reportObject) time.Duration { return 0 })
+
+	ready := true
+	for _, s := range statuses {
+		if s.Status == ReportUnreadable {
+			ready = false
+		}
+	}
+
+	return statuses, ready
+}
+
+// checkReports returns the status of each report, by name, checked at the same time. A report is
+// stale when it was written more than its maxAge before now, or never when it is 0.
+func checkReports(ctx context.Context, stat statFunc, now time.Time, reports map[string]reportObject, maxAge func(reportObject) time.Duration) []ReportStatus {
+	names := sortedKeys(reports)
+	statuses := make([]ReportStatus, len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, r reportObject) {
+			defer wg.Done()
+			statuses[i] = checkReport(ctx, stat, now, r, maxAge(r))
+		}(i, reports[name])
+	}
+	wg.Wait()
+
+	return statuses
+}
+
+func checkReport(ctx context.Context, stat statFunc, now time.Time, r reportObject, maxAge time.Duration) ReportStatus {
+	status := ReportStatus{Report: r.key, Bucket: r.bucket}
+	if maxAge > 0 {
+		status.MaxAge = maxAge.String()
+	}
+
+	lastModified, err := stat(ctx, r.bucket, r.key)
+	if err != nil {
+		status.Status, status.Error = ReportUnreadable, err.Error()
+		if errorStatus(err) == http.StatusNotFound {
+			status.Status = ReportMissing
+		}
+		return status
+	}
+
+	age := now.Sub(lastModified)
+	status.Status = ReportReady
+	status.LastModified = lastModified.UTC().Format(time.RFC3339)
+	status.Age = age.Round(time.Minute).String()
+	status.AgeHours = math.Round(age.Hours()*10) / 10
+	if maxAge > 0 && age > maxAge {
+		status.Status = ReportStale
+	}
+
+	return status
+}
+
+// HealthzPage is the liveness of the server, which is alive for as long as it can respond
+func HealthzPage(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("ok\n"))
+}
+
+// ReadyzPage is the readiness of the server, a 503 with the status of each report when it isn't
+// ready to serve them
+func ReadyzPage(w http.ResponseWriter, r *http.Request, readiness *Readiness) {
+	statuses, ready := readiness.Check(r.Context())
+
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, struct {
+		Ready   bool           `json:"ready"`
+		Reports []ReportStatus `json:"reports"`
+	}{ready, statuses})
+}
+
+// VersionPage is the version of the server
+func VersionPage(w http.ResponseWriter, info BuildInfo) {
+	writeJSON(w, http.StatusOK, info)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,118 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+func Test_Readiness_Check(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		err        error
+		written    time.Time
+		wantStatus string
+		wantReady  bool
+	}{
+		{"written recently", nil, now.Add(-time.Hour), ReportReady, true},
+		{"not written yet", &types.NoSuchKey{}, time.Time{}, ReportMissing, true},
+		{"access denied", &smithy.GenericAPIError{Code: "AccessDenied"}, time.Time{}, ReportUnreadable, false},
+		{"timed out", context.DeadlineExceeded, time.Time{}, ReportUnreadable, false},
+		{"written long ago", nil, now.Add(-30 * 24 * time.Hour), ReportReady, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rd := &Readiness{
+				stat: func(ctx context.Context, bucket, key string) (time.Time, error) {
+					if key == "hosted_services.json" {
+						return tt.written, tt.err
+					}
+					return now, nil
+				},
+				reports: pageReports("hoodaw", "apply-live"),
+				timeout: time.Second,
+				now:     func() time.Time { return now },
+			}
+
+			statuses, ready := rd.Check(context.Background())
+			if ready != tt.wantReady {
+				t.Errorf("Check() ready = %v, want %v", ready, tt.wantReady)
+			}
+			if len(statuses) != len(rd.reports) {
+				t.Fatalf("Check() has %d statuses, want %d", len(statuses), len(rd.reports))
+			}
+			for _, s := range statuses {
+				want := ReportReady
+				if s.Report == "hosted_services.json" {
+					want = tt.wantStatus
+				}
+				if s.Status != want {
+					t.Errorf("Check() %s status = %s, want %s", s.Report, s.Status, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadyzPage(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"ready", nil, http.StatusOK},
+		{"unreadable store", &smithy.GenericAPIError{Code: "AccessDenied"}, http.StatusServiceUnavailable},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rd := &Readiness{
+				stat: func(ctx context.Context, bucket, key string) (time.Time, error) {
+					return time.Now(), tt.err
+				},
+				reports: pageReports("hoodaw", "apply-live"),
+				timeout: time.Second,
+				now:     time.Now,
+			}
+
+			w := httptest.NewRecorder()
+			ReadyzPage(w, httptest.NewRequest(http.MethodGet, "/readyz", nil), rd)
+			if w.Code != tt.wantStatus {
+				t.Errorf("ReadyzPage() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			var body struct {
+				Ready   bool           `json:"ready"`
+				Reports []ReportStatus `json:"reports"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Ready != (tt.wantStatus == http.StatusOK) || len(body.Reports) == 0 {
+				t.Errorf("ReadyzPage() body = %+v", body)
+			}
+		})
+	}
+}
+
+func TestVersionPage(t *testing.T) {
+	info := BuildInfo{Version: "1.2.3", Commit: "abc123", GoVersion: "go1.23", StartedAt: "2024-01-10T12:00:00Z"}
+
+	w := httptest.NewRecorder()
+	VersionPage(w, info)
+
+	var got BuildInfo
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got != info {
+		t.Errorf("VersionPage() = %+v, want %+v", got, info)
+	}
+}
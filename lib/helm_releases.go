@@ -2,11 +2,10 @@ package lib
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
-	"text/template"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
 	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
 )
 
@@ -18,43 +17,111 @@ type Cluster struct {
 type HelmReleases struct {
 	Clusters    []Cluster `json:"clusters"`
 	LastUpdated string
+	Cluster     string
+	ClusterList []string
+	State       string
+	States      []string
+	Listing     Listing
 }
 
 type HelmRelease struct {
-	Name             string `json:"name"`
-	Chart            string `json:"chart"`
-	Namespace        string `json:"namespace"`
-	InstalledVersion string `json:"installed_version"`
-	LatestVersion    string `json:"latest_version"`
-	State            string
+	hoodaw.HelmRelease
+	State string
 }
 
-func HelmReleasesPage(w http.ResponseWriter, bucket string, wantJson bool, client *s3.Client) {
-	t := template.Must(template.ParseFiles("lib/templates/helm_releases.html"))
+// helmReleaseRow is a release with its cluster, the rows the releases are filtered and sorted as
+type helmReleaseRow struct {
+	cluster string
+	release HelmRelease
+	owner   HostedService
+}
+
+// helmReleaseColumns are the columns the helm releases can be filtered and sorted by
+var helmReleaseColumns = []column[helmReleaseRow]{
+	{name: "cluster", value: func(r helmReleaseRow) string { return r.cluster }},
+	{name: "namespace", value: func(r helmReleaseRow) string { return r.release.Namespace }},
+	{name: "team", value: func(r helmReleaseRow) string { return r.owner.TeamName }},
+	{name: "business_unit", value: func(r helmReleaseRow) string { return r.owner.BusinessUnit }},
+	{name: "name", value: func(r helmReleaseRow) string { return r.release.Name }},
+	{name: "chart", value: func(r helmReleaseRow) string { return r.release.Chart }},
+	{name: "installed_version", value: func(r helmReleaseRow) string { return r.release.InstalledVersion }},
+	{name: "latest_version", value: func(r helmReleaseRow) string { return r.release.LatestVersion }},
+	{name: "state", value: func(r helmReleaseRow) string { return r.release.State }},
+}
 
-	byteValue, filestamp, err := utils.ImportS3File(client, bucket, "helm_releases.json")
+// HelmReleasesPage shows the releases of each cluster, filtered, sorted and paged by the query
+func HelmReleasesPage(w http.ResponseWriter, bucket string, query Query, format Format, client *s3.Client) error {
+	var helmReleases HelmReleases
+	byteValue, filestamp, err := readReport(s3Load(client), bucket, "helm_releases.json", &helmReleases)
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
 
-	if wantJson {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(byteValue)
-		return
+	helmReleases.LastUpdated = filestamp
+	helmReleases.Cluster = query.Filters["cluster"]
+	helmReleases.State = query.Filters["state"]
+
+	owners := loadNamespaceOwners(client, bucket)
+
+	rows := make([]helmReleaseRow, 0)
+	clusters := make([]string, 0)
+	states := make([]string, 0)
+	for _, c := range helmReleases.Clusters {
+		clusters = append(clusters, c.ClusterName)
+		for _, h := range c.HelmReleases {
+			h.State = utils.CompareVersions(h.InstalledVersion, h.LatestVersion)
+			states = append(states, h.State)
+			rows = append(rows, helmReleaseRow{cluster: c.ClusterName, release: h, owner: owners.lookup(c.ClusterName, h.Namespace)})
+		}
 	}
+	helmReleases.ClusterList = filterOptions(clusters)
+	helmReleases.States = filterOptions(states)
 
-	var helmReleases HelmReleases
-	json.Unmarshal(byteValue, &helmReleases)
+	matching, err := queryRows(query, rows, helmReleaseColumns)
+	if err != nil {
+		return err
+	}
+	page, listing := paginate(query, matching)
+	helmReleases.Clusters, helmReleases.Listing = groupHelmReleases(page), listing
 
-	helmReleases.LastUpdated = filestamp
+	if format != FormatHTML {
+		return writeRows(w, format, "helm_releases", byteValue, "clusters", helmReleases.Clusters, listing, helmReleasesCSV)
+	}
+
+	return render(w, http.StatusOK, "helm_releases.html", helmReleases)
+}
 
-	for i, c := range helmReleases.Clusters {
-		for j, h := range c.HelmReleases {
-			helmReleases.Clusters[i].HelmReleases[j].State = utils.CompareVersions(h.InstalledVersion, h.LatestVersion)
+// groupHelmReleases groups the rows back into their clusters, in the order of the first release
+// of each cluster
+func groupHelmReleases(rows []helmReleaseRow) []Cluster {
+	clusters := make([]Cluster, 0)
+	index := make(map[string]int)
+	for _, r := range rows {
+		i, ok := index[r.cluster]
+		if !ok {
+			i = len(clusters)
+			index[r.cluster] = i
+			clusters = append(clusters, Cluster{ClusterName: r.cluster})
 		}
+		clusters[i].HelmReleases = append(clusters[i].HelmReleases, r.release)
 	}
 
-	if err := t.ExecuteTemplate(w, "helm_releases.html", helmReleases); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	return clusters
+}
+
+// helmReleasesCSV is a row for each release of a cluster, with whether it is out of date
+func helmReleasesCSV(data []byte) (csvTable, error) {
+	var helmReleases HelmReleases
+	if err := json.Unmarshal(data, &helmReleases); err != nil {
+		return csvTable{}, err
 	}
+
+	table := csvTable{header: []string{"cluster", "namespace", "name", "chart", "installed_version", "latest_version", "state"}}
+	for _, c := range helmReleases.Clusters {
+		for _, h := range c.HelmReleases {
+			table.rows = append(table.rows, []string{c.ClusterName, h.Namespace, h.Name, h.Chart, h.InstalledVersion, h.LatestVersion, utils.CompareVersions(h.InstalledVersion, h.LatestVersion)})
+		}
+	}
+
+	return table, nil
 }
@@ -11,4 +11,4 @@ require_relative "./filestore"
 require_relative "./costs_by_namespace"
 require_relative "./namespace_usage"
 require_relative "./hosted_services"
-require_relative "./infrastructure_deployments"
\ No newline at end of file
+require_relative "./report_freshness"
\ No newline at end of file
@@ -2,12 +2,10 @@ package lib
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
-	"text/template"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
 )
 
 type HostedServices struct {
@@ -15,49 +13,83 @@ type HostedServices struct {
 	TotalNamespaces    int
 	UniqueApplications int
 	LastUpdated        string
+	Cluster            string
+	Clusters           []string
+	Environment        string
+	Environments       []string
+	Listing            Listing
 }
 
-type HostedService struct {
-	Namespace    string   `json:"Name"`
-	Application  string   `json:"Application"`
-	BusinessUnit string   `json:"BusinessUnit"`
-	TeamName     string   `json:"TeamName"`
-	SlackChannel string   `json:"TeamSlackChannel"`
-	SourceCode   string   `json:"GithubURL"`
-	DomainNames  []string `json:"DomainNames"`
-}
+type HostedService = hoodaw.HostedService
+
+type IngressHost = hoodaw.IngressHost
 
-func HostedServicesPage(w http.ResponseWriter, bucket string, wantJson bool, client *s3.Client) {
-	t := template.Must(template.ParseFiles("lib/templates/hosted_services.html"))
+// hostedServiceColumns are the columns the hosted services can be filtered and sorted by
+var hostedServiceColumns = []column[HostedService]{
+	{name: "namespace", value: func(s HostedService) string { return s.Namespace }},
+	{name: "cluster", value: func(s HostedService) string { return s.Cluster }},
+	{name: "environment", value: func(s HostedService) string { return s.DeploymentType }},
+	{name: "application", value: func(s HostedService) string { return s.Application }},
+	{name: "business_unit", value: func(s HostedService) string { return s.BusinessUnit }},
+	{name: "team", value: func(s HostedService) string { return s.TeamName }},
+	{name: "slack_channel", value: func(s HostedService) string { return s.SlackChannel }},
+	{name: "source_code", value: func(s HostedService) string { return s.SourceCode }},
+}
 
-	byteValue, filestamp, err := utils.ImportS3File(client, bucket, "hosted_services.json")
+// HostedServicesPage lists the namespaces, filtered, sorted and paged by the query
+func HostedServicesPage(w http.ResponseWriter, bucket string, query Query, format Format, client *s3.Client) error {
+	var hostedServices HostedServices
+	byteValue, filestamp, err := readReport(s3Load(client), bucket, "hosted_services.json", &hostedServices)
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
 
-	if wantJson {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(byteValue)
-		return
+	hostedServices.LastUpdated = filestamp
+	hostedServices.Cluster = query.Filters["cluster"]
+	hostedServices.Environment = query.Filters["environment"]
+
+	clusters := make([]string, 0)
+	environments := make([]string, 0)
+	for _, hs := range hostedServices.HostedServices {
+		clusters = append(clusters, hs.Cluster)
+		environments = append(environments, hs.DeploymentType)
 	}
+	hostedServices.Clusters = filterOptions(clusters)
+	hostedServices.Environments = filterOptions(environments)
 
-	var hostedServices HostedServices
-	json.Unmarshal(byteValue, &hostedServices)
+	matching, err := queryRows(query, hostedServices.HostedServices, hostedServiceColumns)
+	if err != nil {
+		return err
+	}
+	hostedServices.HostedServices, hostedServices.Listing = paginate(query, matching)
 
-	hostedServices.LastUpdated = filestamp
+	if format != FormatHTML {
+		return writeRows(w, format, "hosted_services", byteValue, "namespace_details", hostedServices.HostedServices, hostedServices.Listing, hostedServicesCSV)
+	}
 
 	countNS := make(map[string]int)
 	countApp := make(map[string]int)
+	for _, hs := range matching {
+		countNS[hs.Namespace]++
+		countApp[hs.Application]++
+	}
+	hostedServices.TotalNamespaces = len(countNS)
+	hostedServices.UniqueApplications = len(countApp)
 
-	for i := 0; i < len(hostedServices.HostedServices); i++ {
-		countNS[hostedServices.HostedServices[i].Namespace]++
-		countApp[hostedServices.HostedServices[i].Application]++
+	return render(w, http.StatusOK, "hosted_services.html", hostedServices)
+}
 
-		hostedServices.TotalNamespaces = len(countNS)
-		hostedServices.UniqueApplications = len(countApp)
+// hostedServicesCSV is a row for each namespace of a cluster
+func hostedServicesCSV(data []byte) (csvTable, error) {
+	var hostedServices HostedServices
+	if err := json.Unmarshal(data, &hostedServices); err != nil {
+		return csvTable{}, err
 	}
 
-	if err := t.ExecuteTemplate(w, "hosted_services.html", hostedServices); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	table := csvTable{header: []string{"cluster", "namespace", "application", "business_unit", "team", "slack_channel", "source_code", "domain_names", "deployment_type"}}
+	for _, s := range hostedServices.HostedServices {
+		table.rows = append(table.rows, []string{s.Cluster, s.Namespace, s.Application, s.BusinessUnit, s.TeamName, s.SlackChannel, s.SourceCode, joinCSV(s.DomainNames), s.DeploymentType})
 	}
+
+	return table, nil
 }
@@ -0,0 +1,88 @@
+package lib
+
+import (
+	"encoding/json"
+	"html/template"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
+)
+
+type InfrastructureDeployments struct {
+	hoodaw.InfrastructureDeployments
+	FailurePercent float64     `json:"-"`
+	WeeklyChart    template.JS `json:"-"`
+	LastUpdated    string      `json:"-"`
+}
+
+// MonthlyDeployments is the number of deployments and reverts merged in a month, kept as the
+// strings the report has always written
+type MonthlyDeployments = hoodaw.MonthlyDeployments
+
+// DoraMetrics are the delivery metrics of the deployments over the period, the times in hours
+type DoraMetrics = hoodaw.DoraMetrics
+
+type DeploymentWeek = hoodaw.DeploymentWeek
+
+// DeploymentFailure is a deployment which was reverted or caused an incident, Reason is reverted,
+// incident or revert
+type DeploymentFailure = hoodaw.DeploymentFailure
+
+// InfrastructureDeploymentsPage charts the DORA metrics of the infrastructure deployments week by
+// week, with the failed deployments and the monthly counts
+func InfrastructureDeploymentsPage(w http.ResponseWriter, bucket string, format Format, client *s3.Client) error {
+	var deployments InfrastructureDeployments
+	byteValue, filestamp, err := readReport(s3Load(client), bucket, "infrastructure_deployments.json", &deployments)
+	if err != nil {
+		return err
+	}
+
+	if format != FormatHTML {
+		return writeReport(w, format, "infrastructure_deployments", byteValue, infrastructureDeploymentsCSV)
+	}
+
+	deployments.LastUpdated = filestamp
+	deployments.FailurePercent = math.Round(deployments.Metrics.ChangeFailureRate * 100)
+
+	chart, err := weeklyChart(deployments.Weeks)
+	if err != nil {
+		return err
+	}
+	deployments.WeeklyChart = template.JS(chart)
+
+	return render(w, http.StatusOK, "infrastructure_deployments.html", deployments)
+}
+
+// weeklyChart returns the rows of the weekly chart as a javascript array of the week, the
+// deployments, the failures and the lead time
+func weeklyChart(weeks []DeploymentWeek) (string, error) {
+	rows := make([][]interface{}, 0, len(weeks))
+	for _, w := range weeks {
+		rows = append(rows, []interface{}{w.Start, w.Deployments, w.Failures, w.LeadTimeHours})
+	}
+
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// infrastructureDeploymentsCSV is a row for each week of deployments with its DORA metrics
+func infrastructureDeploymentsCSV(data []byte) (csvTable, error) {
+	var deployments InfrastructureDeployments
+	if err := json.Unmarshal(data, &deployments); err != nil {
+		return csvTable{}, err
+	}
+
+	table := csvTable{header: []string{"week_start", "deployments", "failures", "lead_time_hours"}}
+	for _, week := range deployments.Weeks {
+		table.rows = append(table.rows, []string{week.Start, strconv.Itoa(week.Deployments), strconv.Itoa(week.Failures), formatFloat(week.LeadTimeHours)})
+	}
+
+	return table, nil
+}
@@ -0,0 +1,32 @@
+package lib
+
+import "testing"
+
+func Test_weeklyChart(t *testing.T) {
+	tests := []struct {
+		name  string
+		weeks []DeploymentWeek
+		want  string
+	}{
+		{"no weeks", nil, "[]"},
+		{
+			"weeks",
+			[]DeploymentWeek{
+				{Start: "2024-01-01", Deployments: 2, Failures: 1, LeadTimeHours: 7.5},
+				{Start: "2024-01-08"},
+			},
+			`[["2024-01-01",2,1,7.5],["2024-01-08",0,0,0]]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := weeklyChart(tt.weeks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("weeklyChart() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
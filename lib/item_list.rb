@@ -20,6 +20,11 @@ class ItemList
     list.length
   end
 
+  # the path the report is posted to
+  def name
+    File.basename(@json_file, ".json")
+  end
+
   private
 
   def data
@@ -0,0 +1,295 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultPerPage is the rows of a html page when per_page isn't set, the data formats have all
+	// of the rows unless a page is asked for
+	defaultPerPage = 100
+	maxPerPage     = 1000
+)
+
+// reservedParameters are the query parameters which aren't filters, q is the words of a search
+var reservedParameters = map[string]bool{"sort": true, "page": true, "per_page": true, "format": true, "q": true}
+
+// Query is the filtering, sorting and pagination of the rows of a report asked for by the query
+// parameters of a request. Any other parameter is the name of a column, filtering the rows to
+// those with its value, e.g. ?team=webops&sort=-namespace&page=2&per_page=50. Sort is a column,
+// prefixed with - to sort descending.
+type Query struct {
+	Filters map[string]string
+	Sort    string
+	Desc    bool
+	Page    int
+	PerPage int
+
+	values url.Values
+}
+
+// ParseQuery returns the query of the parameters of a request for a page in the format
+func ParseQuery(values url.Values, format Format) (Query, error) {
+	q := Query{Filters: make(map[string]string), Page: 1, values: values}
+
+	for name := range values {
+		if !reservedParameters[name] && values.Get(name) != "" {
+			q.Filters[name] = values.Get(name)
+		}
+	}
+
+	q.Sort = values.Get("sort")
+	if strings.HasPrefix(q.Sort, "-") {
+		q.Sort, q.Desc = q.Sort[1:], true
+	}
+
+	var err error
+	if v := values.Get("page"); v != "" {
+		if q.Page, err = strconv.Atoi(v); err != nil || q.Page < 1 {
+			return Query{}, fmt.Errorf("invalid page %q, pages start from 1", v)
+		}
+	}
+	if v := values.Get("per_page"); v != "" {
+		if q.PerPage, err = strconv.Atoi(v); err != nil || q.PerPage < 1 || q.PerPage > maxPerPage {
+			return Query{}, fmt.Errorf("invalid per_page %q, it is from 1 to %d", v, maxPerPage)
+		}
+	}
+
+	if q.PerPage == 0 && (format == FormatHTML || values.Has("page")) {
+		q.PerPage = defaultPerPage
+	}
+
+	return q, nil
+}
+
+// column is a field of the rows of a report which they can be filtered and sorted by. A filter
+// matches the value case insensitively, or any of the values of a column of several, e.g. the
+// clusters of a namespace. Columns sort numerically when both values are numbers.
+type column[T any] struct {
+	name   string
+	value  func(T) string
+	values func(T) []string
+}
+
+func (c column[T]) matches(row T, filter string) bool {
+	if c.values != nil {
+		for _, v := range c.values(row) {
+			if strings.EqualFold(v, filter) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return strings.EqualFold(c.value(row), filter)
+}
+
+// queryRows returns the rows matching the filters of the query in its order, the order of the
+// report when it isn't sorted. It is an error to filter or sort by a column the report hasn't got.
+func queryRows[T any](q Query, rows []T, columns []column[T]) ([]T, error) {
+	byName := make(map[string]column[T])
+	names := make([]string, 0, len(columns))
+	for _, c := range columns {
+		byName[c.name] = c
+		names = append(names, c.name)
+	}
+
+	for name := range q.Filters {
+		if _, ok := byName[name]; !ok {
+			return nil, &StatusError{Status: http.StatusBadRequest, Err: fmt.Errorf("unknown filter %q, the columns are %s", name, strings.Join(names, ", "))}
+		}
+	}
+	sortBy, ok := byName[q.Sort]
+	if q.Sort != "" && !ok {
+		return nil, &StatusError{Status: http.StatusBadRequest, Err: fmt.Errorf("unknown sort %q, the columns are %s", q.Sort, strings.Join(names, ", "))}
+	}
+
+	matching := make([]T, 0, len(rows))
+	for _, row := range rows {
+		match := true
+		for name, filter := range q.Filters {
+			if !byName[name].matches(row, filter) {
+				match = false
+				break
+			}
+		}
+		if match {
+			matching = append(matching, row)
+		}
+	}
+
+	if q.Sort != "" {
+		sort.SliceStable(matching, func(i, j int) bool {
+			a, b := sortBy.value(matching[i]), sortBy.value(matching[j])
+			if q.Desc {
+				a, b = b, a
+			}
+			return lessValue(a, b)
+		})
+	}
+
+	return matching, nil
+}
+
+// lessValue orders numbers numerically and anything else alphabetically, ignoring case
+func lessValue(a, b string) bool {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return x < y
+	}
+
+	return strings.ToLower(a) < strings.ToLower(b)
+}
+
+// paginate returns the page of the rows asked for by the query, all of them when it doesn't
+// ask for a page, with the listing of the page
+func paginate[T any](q Query, rows []T) ([]T, Listing) {
+	l := Listing{Query: q, Total: len(rows), Pages: 1}
+	if q.PerPage == 0 {
+		return rows, l
+	}
+
+	l.Pages = (len(rows) + q.PerPage - 1) / q.PerPage
+	if l.Pages == 0 {
+		l.Pages = 1
+	}
+
+	start := (q.Page - 1) * q.PerPage
+	if start > len(rows) {
+		start = len(rows)
+	}
+	end := start + q.PerPage
+	if end > len(rows) {
+		end = len(rows)
+	}
+
+	return rows[start:end], l
+}
+
+// Listing is a page of the rows of a report, with the links to sort and page through them
+type Listing struct {
+	Query
+	Total int
+	Pages int
+}
+
+// SortURL is the link sorting by the column, descending when it is already sorted ascending by it
+func (l Listing) SortURL(column string) string {
+	sort := column
+	if l.Sort == column && !l.Desc {
+		sort = "-" + column
+	}
+
+	return l.url(map[string]string{"sort": sort, "page": ""})
+}
+
+// SortedBy is the arrow of the column the rows are sorted by
+func (l Listing) SortedBy(column string) string {
+	switch {
+	case l.Sort != column:
+		return ""
+	case l.Desc:
+		return "▼"
+	default:
+		return "▲"
+	}
+}
+
+// PageURL is the link to a page of the rows
+func (l Listing) PageURL(page int) string {
+	return l.url(map[string]string{"page": strconv.Itoa(page)})
+}
+
+func (l Listing) HasPrevious() bool { return l.Page > 1 }
+func (l Listing) HasNext() bool     { return l.Page < l.Pages }
+func (l Listing) Previous() int     { return l.Page - 1 }
+func (l Listing) Next() int         { return l.Page + 1 }
+
+// First and Last are the numbers of the first and last rows of the page
+func (l Listing) First() int {
+	if l.Total == 0 {
+		return 0
+	}
+	return min((l.Page-1)*l.PerPage+1, l.Total)
+}
+
+func (l Listing) Last() int {
+	if l.PerPage == 0 {
+		return l.Total
+	}
+	return min(l.Page*l.PerPage, l.Total)
+}
+
+// url is the query of the request with the parameters set, or removed when empty
+func (l Listing) url(set map[string]string) string {
+	values := url.Values{}
+	for k, v := range l.values {
+		values[k] = v
+	}
+	for k, v := range set {
+		if v == "" {
+			values.Del(k)
+		} else {
+			values.Set(k, v)
+		}
+	}
+
+	return "?" + values.Encode()
+}
+
+// setHeaders sets the X-Total-Count of the rows matching the filters and the Link to the next
+// and previous pages of the data formats, whose body is the page of rows in the shape of the report
+func (l Listing) setHeaders(w http.ResponseWriter) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(l.Total))
+
+	links := make([]string, 0, 2)
+	if l.HasPrevious() {
+		links = append(links, fmt.Sprintf("<%s>; rel=\"prev\"", l.PageURL(l.Previous())))
+	}
+	if l.HasNext() {
+		links = append(links, fmt.Sprintf("<%s>; rel=\"next\"", l.PageURL(l.Next())))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// replaceRows returns the json of a report with the list under key replaced by rows, keeping the
+// rest of the report such as its updated_at. An empty key replaces the whole report.
+func replaceRows(data []byte, key string, rows interface{}) ([]byte, error) {
+	if key == "" {
+		return json.Marshal(rows)
+	}
+
+	report := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	report[key] = b
+
+	return json.Marshal(report)
+}
+
+// writeRows writes the data of a report in a data format with the list under key replaced by the
+// page of rows
+func writeRows(w http.ResponseWriter, format Format, name string, data []byte, key string, rows interface{}, l Listing, toCSV func([]byte) (csvTable, error)) error {
+	out, err := replaceRows(data, key, rows)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	l.setHeaders(w)
+	return writeReport(w, format, name, out, toCSV)
+}
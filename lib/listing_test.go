@@ -0,0 +1,217 @@
+package lib
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		format  Format
+		want    Query
+		wantErr bool
+	}{
+		{
+			name:   "html pages by default",
+			format: FormatHTML,
+			want:   Query{Filters: map[string]string{}, Page: 1, PerPage: defaultPerPage},
+		},
+		{
+			name:   "data formats have every row",
+			format: FormatJSON,
+			want:   Query{Filters: map[string]string{}, Page: 1},
+		},
+		{
+			name:   "data formats paged when asked",
+			query:  "page=2",
+			format: FormatCSV,
+			want:   Query{Filters: map[string]string{}, Page: 2, PerPage: defaultPerPage},
+		},
+		{
+			name:   "filters and descending sort",
+			query:  "team=webops&cluster=&sort=-namespace&per_page=10&format=json",
+			format: FormatJSON,
+			want:   Query{Filters: map[string]string{"team": "webops"}, Sort: "namespace", Desc: true, Page: 1, PerPage: 10},
+		},
+		{name: "invalid page", query: "page=0", wantErr: true},
+		{name: "invalid per_page", query: "per_page=lots", wantErr: true},
+		{name: "too many per page", query: "per_page=5000", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+
+			got, err := ParseQuery(values, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got.values = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_queryRows(t *testing.T) {
+	rows := []NamespaceCostRow{
+		{Namespace: "ns-b", NamespaceCost: NamespaceCost{Total: 9, Clusters: []string{"live"}}},
+		{Namespace: "ns-a", NamespaceCost: NamespaceCost{Total: 10, Clusters: []string{"live", "live-2"}}},
+		{Namespace: "NS-C", NamespaceCost: NamespaceCost{Total: 1.5, Clusters: []string{"live-2"}}},
+	}
+
+	tests := []struct {
+		name    string
+		query   Query
+		want    []string
+		wantErr bool
+	}{
+		{"report order", Query{}, []string{"ns-b", "ns-a", "NS-C"}, false},
+		{"filter any of the values", Query{Filters: map[string]string{"cluster": "LIVE-2"}}, []string{"ns-a", "NS-C"}, false},
+		{"sort ignoring case", Query{Sort: "namespace"}, []string{"ns-a", "ns-b", "NS-C"}, false},
+		{"sort numbers", Query{Sort: "total"}, []string{"NS-C", "ns-b", "ns-a"}, false},
+		{"sort descending", Query{Sort: "total", Desc: true}, []string{"ns-a", "ns-b", "NS-C"}, false},
+		{"unknown filter", Query{Filters: map[string]string{"colour": "red"}}, nil, true},
+		{"unknown sort", Query{Sort: "colour"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryRows(tt.query, rows, namespaceCostColumns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("queryRows() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var names []string
+			for _, r := range got {
+				names = append(names, r.Namespace)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("queryRows() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func Test_paginate(t *testing.T) {
+	rows := []int{1, 2, 3, 4, 5}
+
+	tests := []struct {
+		name      string
+		query     Query
+		want      []int
+		wantPages int
+		wantFirst int
+		wantLast  int
+	}{
+		{"every row", Query{Page: 1}, []int{1, 2, 3, 4, 5}, 1, 1, 5},
+		{"first page", Query{Page: 1, PerPage: 2}, []int{1, 2}, 3, 1, 2},
+		{"last page", Query{Page: 3, PerPage: 2}, []int{5}, 3, 5, 5},
+		{"past the last page", Query{Page: 4, PerPage: 2}, []int{}, 3, 5, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, listing := paginate(tt.query, rows)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paginate() = %v, want %v", got, tt.want)
+			}
+			if listing.Total != len(rows) || listing.Pages != tt.wantPages {
+				t.Errorf("paginate() listing = %d rows in %d pages, want %d in %d", listing.Total, listing.Pages, len(rows), tt.wantPages)
+			}
+			if listing.First() != tt.wantFirst || listing.Last() != tt.wantLast {
+				t.Errorf("paginate() showing %d to %d, want %d to %d", listing.First(), listing.Last(), tt.wantFirst, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestListing_urls(t *testing.T) {
+	values, _ := url.ParseQuery("cluster=live&sort=namespace&page=2&per_page=10")
+	q, err := ParseQuery(values, FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := Listing{Query: q, Total: 35, Pages: 4}
+
+	if got, want := l.SortURL("namespace"), "?cluster=live&per_page=10&sort=-namespace"; got != want {
+		t.Errorf("SortURL() = %s, want %s", got, want)
+	}
+	if got, want := l.SortURL("team"), "?cluster=live&per_page=10&sort=team"; got != want {
+		t.Errorf("SortURL() = %s, want %s", got, want)
+	}
+
+	w := httptest.NewRecorder()
+	l.setHeaders(w)
+	if got := w.Header().Get("X-Total-Count"); got != "35" {
+		t.Errorf("X-Total-Count = %s, want 35", got)
+	}
+	want := `<?cluster=live&page=1&per_page=10&sort=namespace>; rel="prev", <?cluster=live&page=3&per_page=10&sort=namespace>; rel="next"`
+	if got := w.Header().Get("Link"); got != want {
+		t.Errorf("Link = %s, want %s", got, want)
+	}
+}
+
+func Test_replaceRows(t *testing.T) {
+	data := []byte(`{"updated_at": "2024-01-2 10:4:5 UTC", "namespace_compliance": [{"Name": "a"}, {"Name": "b"}], "namespaces_checked": 2}`)
+
+	got, err := replaceRows(data, "namespace_compliance", []NonCompliantNamespace{{NonCompliantNamespace: hoodaw.NonCompliantNamespace{Name: "b", Issues: []string{"no team"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"namespace_compliance":[{"Name":"b","Cluster":"","TeamName":"","TeamSlackChannel":"","Issues":["no team"]}],"namespaces_checked":2,"updated_at":"2024-01-2 10:4:5 UTC"}`
+	if string(got) != want {
+		t.Errorf("replaceRows() = %s, want %s", got, want)
+	}
+}
+
+// every report with namespaces can be filtered by the team and business unit which own them
+func Test_columns_teamAndBusinessUnit(t *testing.T) {
+	for name, columns := range map[string][]string{
+		"certificate_expiry":   columnNames(certificateColumns),
+		"erroring_namespaces":  columnNames(erroringNamespaceColumns(nil)),
+		"helm_releases":        columnNames(helmReleaseColumns),
+		"hosted_services":      columnNames(hostedServiceColumns),
+		"live_one_domains":     columnNames(liveOneDomainColumns),
+		"namespace_compliance": columnNames(nonCompliantNamespaceColumns),
+		"namespace_costs":      columnNames(namespaceCostColumns),
+	} {
+		if !slices.Contains(columns, "team") || !slices.Contains(columns, "business_unit") {
+			t.Errorf("%s columns = %v, want team and business_unit", name, columns)
+		}
+	}
+
+	rows := []helmReleaseRow{
+		{cluster: "live", release: HelmRelease{HelmRelease: hoodaw.HelmRelease{Name: "tracker-api"}}, owner: HostedService{TeamName: "webops", BusinessUnit: "Platforms"}},
+		{cluster: "live", release: HelmRelease{HelmRelease: hoodaw.HelmRelease{Name: "payments"}}, owner: HostedService{TeamName: "finance-dev", BusinessUnit: "HMPPS"}},
+	}
+	q, err := ParseQuery(url.Values{"business_unit": {"platforms"}}, FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := queryRows(q, rows, helmReleaseColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].release.Name != "tracker-api" {
+		t.Errorf("queryRows() = %+v, want the release of the Platforms business unit", got)
+	}
+}
+
+func columnNames[T any](columns []column[T]) []string {
+	names := make([]string, 0, len(columns))
+	for _, c := range columns {
+		names = append(names, c.name)
+	}
+	return names
+}
@@ -2,46 +2,182 @@ package lib
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
-	"text/template"
+	"sort"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
 )
 
 type Domains struct {
-	Data []struct {
-		Namespace         string `json:"namespace"`
-		IngressName       string `json:"ingress"`
-		URL               string `json:"hostname"`
-		CreationTimestamp string `json:"CreatedAt"`
-	} `json:"live_one_domains"`
-	LastUpdated string `json:"last_updated"`
+	Data        []Domain `json:"live_one_domains"`
+	LastUpdated string   `json:"last_updated"`
 	Total       int
+	Cluster     string
+	Clusters    []string
+	Migrations  []DomainMigration
+	Dangling    []ReachableDomain `json:"-"`
+	Listing     Listing
 }
 
-func LiveOneDomainsPage(w http.ResponseWriter, bucket string, wantJson bool, client *s3.Client) {
-	t := template.Must(template.ParseFiles("lib/templates/live_one_domains.html"))
+type Domain struct {
+	hoodaw.Domain
+	Team         string `json:"-"`
+	BusinessUnit string `json:"-"`
+}
+
+// DomainMigration is the hosts still to be moved off a deprecated domain, by owning team
+type DomainMigration struct {
+	Pattern     string
+	Deadline    string
+	DaysLeft    int
+	DaysOverdue int
+	Overdue     bool
+	Total       int
+	Teams       []TeamDomains
+}
+
+type TeamDomains struct {
+	Team    string
+	Domains []Domain
+}
 
-	byteValue, filestamp, err := utils.ImportS3File(client, bucket, "live_one_domains.json")
+// liveOneDomainColumns are the columns the domains can be filtered and sorted by
+var liveOneDomainColumns = []column[Domain]{
+	{name: "cluster", value: func(d Domain) string { return d.Cluster }},
+	{name: "namespace", value: func(d Domain) string { return d.Namespace }},
+	{name: "team", value: func(d Domain) string { return d.Team }},
+	{name: "business_unit", value: func(d Domain) string { return d.BusinessUnit }},
+	{name: "ingress", value: func(d Domain) string { return d.IngressName }},
+	{name: "hostname", value: func(d Domain) string { return d.URL }},
+	{name: "pattern", value: func(d Domain) string { return d.Pattern }},
+	{name: "deadline", value: func(d Domain) string { return d.Deadline }},
+	{name: "created_at", value: func(d Domain) string { return d.CreationTimestamp }},
+}
+
+// LiveOneDomainsPage tracks the migration of ingress hosts off deprecated domains, grouped by
+// the domain pattern they match and the team which owns their namespace. The hosts are filtered,
+// sorted and paged by the query, and the migrations cover every host which matches it. The
+// dangling domains of the domain-reachability report are shown with them, when it can be read.
+func LiveOneDomainsPage(w http.ResponseWriter, bucket string, query Query, format Format, client *s3.Client) error {
+	var domains Domains
+	byteValue, filestamp, err := readReport(s3Load(client), bucket, "live_one_domains.json", &domains)
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
 
-	if wantJson {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(byteValue)
-		return
+	owners := loadNamespaceOwners(client, bucket)
+
+	domains.LastUpdated = filestamp
+	domains, err = buildLiveOneDomains(domains, owners, query, time.Now())
+	if err != nil {
+		return err
 	}
 
-	var domains Domains
-	json.Unmarshal(byteValue, &domains)
+	if format != FormatHTML {
+		return writeRows(w, format, "live_one_domains", byteValue, "live_one_domains", domains.Data, domains.Listing, liveOneDomainsCSV)
+	}
 
-	domains.LastUpdated = filestamp
-	domains.Total = len(domains.Data)
+	var reachability DomainReachability
+	if _, _, err := readReport(s3Load(client), bucket, "domain_reachability.json", &reachability); err != nil {
+		logReportErrors(map[string]error{"domain_reachability": err})
+	}
+	domains.Dangling = danglingDomains(reachability, owners, domains.Cluster)
+
+	return render(w, http.StatusOK, "live_one_domains.html", domains)
+}
+
+// buildLiveOneDomains tags the domains with their team, groups those matching the query into
+// migrations and pages them
+func buildLiveOneDomains(domains Domains, owners namespaceOwners, query Query, now time.Time) (Domains, error) {
+	domains.Cluster = query.Filters["cluster"]
+
+	clusters := make([]string, 0)
+	for i, d := range domains.Data {
+		clusters = append(clusters, d.Cluster)
+		owner := owners.lookup(d.Cluster, d.Namespace)
+		domains.Data[i].Team, domains.Data[i].BusinessUnit = owner.TeamName, owner.BusinessUnit
+	}
+	domains.Clusters = filterOptions(clusters)
 
-	if err := t.ExecuteTemplate(w, "live_one_domains.html", domains); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	matching, err := queryRows(query, domains.Data, liveOneDomainColumns)
+	if err != nil {
+		return domains, err
 	}
+
+	domains.Total = len(matching)
+	domains.Migrations = groupDomainMigrations(matching, now)
+	domains.Data, domains.Listing = paginate(query, matching)
+
+	return domains, nil
+}
+
+// groupDomainMigrations groups the domains by pattern, soonest deadline first, then by team.
+// Reports from before patterns were added are grouped under the live-1 domain.
+func groupDomainMigrations(domains []Domain, now time.Time) []DomainMigration {
+	byPattern := make(map[string]map[string][]Domain)
+	deadlines := make(map[string]string)
+
+	for _, d := range domains {
+		if d.Pattern == "" {
+			d.Pattern = "live-1.cloud-platform.service.justice.gov.uk"
+		}
+		if byPattern[d.Pattern] == nil {
+			byPattern[d.Pattern] = make(map[string][]Domain)
+		}
+		byPattern[d.Pattern][d.Team] = append(byPattern[d.Pattern][d.Team], d)
+		deadlines[d.Pattern] = d.Deadline
+	}
+
+	migrations := make([]DomainMigration, 0)
+	for pattern, teams := range byPattern {
+		m := DomainMigration{Pattern: pattern, Deadline: deadlines[pattern]}
+
+		if deadline, err := time.Parse("2006-01-02", m.Deadline); err == nil {
+			m.Overdue = now.After(deadline)
+			if m.Overdue {
+				m.DaysOverdue = int(now.Sub(deadline).Hours() / 24)
+			} else {
+				m.DaysLeft = int(deadline.Sub(now).Hours() / 24)
+			}
+		}
+
+		for team, teamDomains := range teams {
+			m.Teams = append(m.Teams, TeamDomains{Team: team, Domains: teamDomains})
+			m.Total += len(teamDomains)
+		}
+		sort.Slice(m.Teams, func(i, j int) bool { return m.Teams[i].Team < m.Teams[j].Team })
+
+		migrations = append(migrations, m)
+	}
+
+	// patterns without a deadline go last
+	sort.Slice(migrations, func(i, j int) bool {
+		a, b := migrations[i], migrations[j]
+		if (a.Deadline == "") != (b.Deadline == "") {
+			return b.Deadline == ""
+		}
+		if a.Deadline != b.Deadline {
+			return a.Deadline < b.Deadline
+		}
+		return a.Pattern < b.Pattern
+	})
+
+	return migrations
+}
+
+// liveOneDomainsCSV is a row for each host still to be migrated
+func liveOneDomainsCSV(data []byte) (csvTable, error) {
+	var domains Domains
+	if err := json.Unmarshal(data, &domains); err != nil {
+		return csvTable{}, err
+	}
+
+	table := csvTable{header: []string{"cluster", "namespace", "ingress", "hostname", "pattern", "deadline", "created_at"}}
+	for _, d := range domains.Data {
+		table.rows = append(table.rows, []string{d.Cluster, d.Namespace, d.IngressName, d.URL, d.Pattern, d.Deadline, d.CreationTimestamp})
+	}
+
+	return table, nil
 }
@@ -0,0 +1,128 @@
+package lib
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
+)
+
+func Test_groupDomainMigrations(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	domains := []Domain{
+		{Domain: hoodaw.Domain{URL: "a.apps.live.cloud-platform.service.justice.gov.uk", Pattern: "apps.live.cloud-platform.service.justice.gov.uk", Deadline: "2025-03-31"}, Team: "webops"},
+		{Domain: hoodaw.Domain{URL: "b.apps.live.cloud-platform.service.justice.gov.uk", Pattern: "apps.live.cloud-platform.service.justice.gov.uk", Deadline: "2025-03-31"}, Team: "analytics"},
+		{Domain: hoodaw.Domain{URL: "c.live-1.cloud-platform.service.justice.gov.uk"}, Team: "webops"},
+		{Domain: hoodaw.Domain{URL: "d.old.service.justice.gov.uk", Pattern: "old.service.justice.gov.uk", Deadline: "2025-01-01"}, Team: "webops"},
+	}
+
+	want := []DomainMigration{
+		{
+			Pattern:     "old.service.justice.gov.uk",
+			Deadline:    "2025-01-01",
+			DaysOverdue: 59,
+			Overdue:     true,
+			Total:       1,
+			Teams:       []TeamDomains{{Team: "webops", Domains: []Domain{domains[3]}}},
+		},
+		{
+			Pattern:  "apps.live.cloud-platform.service.justice.gov.uk",
+			Deadline: "2025-03-31",
+			DaysLeft: 29,
+			Total:    2,
+			Teams: []TeamDomains{
+				{Team: "analytics", Domains: []Domain{domains[1]}},
+				{Team: "webops", Domains: []Domain{domains[0]}},
+			},
+		},
+		{
+			Pattern: "live-1.cloud-platform.service.justice.gov.uk",
+			Total:   1,
+			Teams: []TeamDomains{{Team: "webops", Domains: []Domain{
+				{Domain: hoodaw.Domain{URL: "c.live-1.cloud-platform.service.justice.gov.uk", Pattern: "live-1.cloud-platform.service.justice.gov.uk"}, Team: "webops"},
+			}}},
+		},
+	}
+
+	if got := groupDomainMigrations(domains, now); !reflect.DeepEqual(got, want) {
+		t.Errorf("groupDomainMigrations() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_buildLiveOneDomains_groupsEveryPage(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var domains Domains
+	for i := 0; i < 25; i++ {
+		namespace := "ns-one"
+		if i%5 == 0 {
+			namespace = "ns-two"
+		}
+		domains.Data = append(domains.Data, Domain{Domain: hoodaw.Domain{
+			Cluster:   "live",
+			Namespace: namespace,
+			URL:       fmt.Sprintf("host-%02d.apps.live-1.cloud-platform.service.justice.gov.uk", i),
+			Pattern:   "live-1.cloud-platform.service.justice.gov.uk",
+			Deadline:  "2025-01-01",
+		}})
+	}
+	owners := newNamespaceOwners([]HostedService{
+		{Namespace: "ns-one", Cluster: "live", TeamName: "webops"},
+		{Namespace: "ns-two", Cluster: "live", TeamName: "analytics"},
+	})
+
+	query, err := ParseQuery(url.Values{"per_page": {"10"}}, FormatHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := buildLiveOneDomains(domains, owners, query, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Data) != 10 || got.Total != 25 || got.Listing.Pages != 3 {
+		t.Errorf("buildLiveOneDomains() has %d of %d hosts on %d pages, want 10 of 25 on 3", len(got.Data), got.Total, got.Listing.Pages)
+	}
+	if len(got.Migrations) != 1 {
+		t.Fatalf("buildLiveOneDomains() has %d migrations, want 1", len(got.Migrations))
+	}
+
+	m := got.Migrations[0]
+	if m.Total != 25 || m.DaysOverdue != 59 {
+		t.Errorf("migration total = %d, days overdue = %d, want 25 and 59", m.Total, m.DaysOverdue)
+	}
+	teams := make(map[string]int)
+	for _, td := range m.Teams {
+		teams[td.Team] = len(td.Domains)
+	}
+	if !reflect.DeepEqual(teams, map[string]int{"analytics": 5, "webops": 20}) {
+		t.Errorf("migration teams = %v, want analytics 5 and webops 20", teams)
+	}
+}
+
+func Test_danglingDomains(t *testing.T) {
+	reachability := DomainReachability{Domains: []hoodaw.ReachableDomain{
+		{Cluster: "live", Namespace: "ns-one", Hostname: "b.service.justice.gov.uk", Status: "dangling"},
+		{Cluster: "live", Namespace: "ns-one", Hostname: "ok.service.justice.gov.uk", Status: "ok"},
+		{Cluster: "live", Namespace: "ns-two", Hostname: "a.service.justice.gov.uk", Status: "dangling"},
+		{Cluster: "live-2", Namespace: "ns-one", Hostname: "c.service.justice.gov.uk", Status: "dangling"},
+	}}
+	owners := newNamespaceOwners([]HostedService{{Namespace: "ns-one", Cluster: "live", TeamName: "webops"}})
+
+	var got []string
+	for _, d := range danglingDomains(reachability, owners, "live") {
+		got = append(got, d.Hostname+" "+d.Team)
+	}
+	if want := []string{"a.service.justice.gov.uk ", "b.service.justice.gov.uk webops"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("danglingDomains() = %q, want %q", got, want)
+	}
+
+	if got := danglingDomains(reachability, owners, ""); len(got) != 3 {
+		t.Errorf("danglingDomains() of every cluster = %d, want 3", len(got))
+	}
+}
@@ -0,0 +1,211 @@
+package lib
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// storeFetchErrors counts the reports which couldn't be read from the store, by their key and the
+// status a page fails with for the error, e.g. 404 for a report which hasn't been written
+var storeFetchErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "hoodaw_store_fetch_errors_total",
+	Help: "Reports which couldn't be read from the store, by report and status.",
+}, []string{"report", "status"})
+
+func countFetchError(key string, err error) {
+	if err != nil {
+		storeFetchErrors.WithLabelValues(key, strconv.Itoa(errorStatus(err))).Inc()
+	}
+}
+
+// Metrics are the prometheus metrics of the requests to the server and of the data of the
+// reports, served by /metrics
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+
+	reportErrors       *prometheus.CounterVec
+	namespaceCost      *prometheus.GaugeVec
+	helmOutOfDate      *prometheus.GaugeVec
+	liveOneDomains     prometheus.Gauge
+	erroringNamespaces prometheus.Gauge
+}
+
+// NewMetrics returns the metrics of the server, along with the age of each report checked by
+// freshness when they are scraped
+func NewMetrics(freshness *FreshnessChecker) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hoodaw_http_requests_total",
+			Help: "Requests to the server, by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "hoodaw_http_request_duration_seconds",
+			Help:    "How long requests to the server took, by route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route"}),
+		reportErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hoodaw_report_errors_total",
+			Help: "Reports which weren't valid json when their metrics were updated, by report.",
+		}, []string{"report"}),
+		namespaceCost: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "hoodaw_namespace_monthly_cost_dollars",
+			Help: "The monthly cost of each namespace, from the namespace costs report.",
+		}, []string{"namespace", "team"}),
+		helmOutOfDate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "hoodaw_helm_releases_out_of_date",
+			Help: "Helm releases behind the latest version of their chart, by cluster and state, from the helm releases report.",
+		}, []string{"cluster", "state"}),
+		liveOneDomains: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "hoodaw_live_one_domains",
+			Help: "Domains still to migrate off live-1, from the live-1 domains report.",
+		}),
+		erroringNamespaces: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "hoodaw_erroring_namespaces",
+			Help: "Namespaces failing to apply in the latest apply-live run.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		storeFetchErrors,
+		m.requests, m.duration,
+		m.reportErrors, m.namespaceCost, m.helmOutOfDate, m.liveOneDomains, m.erroringNamespaces,
+		&reportAgeCollector{freshness: freshness},
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the prometheus format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Instrument counts and times the requests served by next, by the pattern of the route which
+// served them
+func (m *Metrics) Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(sw, r)
+
+		// the pattern is set by the mux on the request it served, and is empty when no route matched
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		m.requests.WithLabelValues(route, r.Method, strconv.Itoa(sw.status)).Inc()
+		m.duration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusWriter records the status code of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// UpdateReports sets the gauges of the data of the reports from their json, by name, as read by
+// the search index. The gauges of a report which isn't valid json are left as they were.
+func (m *Metrics) UpdateReports(data map[string][]byte) {
+	// the teams of the namespace costs are looked up in the hosted services
+	var hostedServices HostedServices
+	_, hosted := data["hosted_services"]
+	ownersValid := !hosted || m.decodeReport(data, "hosted_services", &hostedServices)
+	owners := newNamespaceOwners(hostedServices.HostedServices)
+
+	var costs Costs
+	if m.decodeReport(data, "namespace_costs", &costs) && ownersValid {
+		m.namespaceCost.Reset()
+		for ns, c := range costs.Namespaces {
+			m.namespaceCost.WithLabelValues(ns, owners.lookup("", ns).TeamName).Set(float64(c.Total))
+		}
+	}
+
+	var helmReleases HelmReleases
+	if m.decodeReport(data, "helm_releases", &helmReleases) {
+		m.helmOutOfDate.Reset()
+		for _, c := range helmReleases.Clusters {
+			for _, h := range c.HelmReleases {
+				if state := utils.CompareVersions(h.InstalledVersion, h.LatestVersion); state != "success" {
+					m.helmOutOfDate.WithLabelValues(c.ClusterName, state).Inc()
+				}
+			}
+		}
+	}
+
+	var domains Domains
+	if m.decodeReport(data, "live_one_domains", &domains) {
+		m.liveOneDomains.Set(float64(len(domains.Data)))
+	}
+
+	var erroringNamespaces []NamespaceError
+	if m.decodeReport(data, "erroring_namespaces", &erroringNamespaces) {
+		m.erroringNamespaces.Set(float64(len(erroringNamespaces)))
+	}
+}
+
+// decodeReport decodes the json of the report, by name, into v, reporting whether the report was
+// read and is valid. One which isn't valid is logged and counted.
+func (m *Metrics) decodeReport(data map[string][]byte, name string, v any) bool {
+	if _, ok := data[name]; !ok {
+		return false
+	}
+
+	if err := decodeReport(data, name, v); err != nil {
+		slog.Warn("keeping the metrics of report", "report", name, "error", err)
+		m.reportErrors.WithLabelValues(name).Inc()
+		return false
+	}
+
+	return true
+}
+
+// reportAgeCollector checks how long ago each report was written when the metrics are scraped
+type reportAgeCollector struct {
+	freshness *FreshnessChecker
+}
+
+var (
+	reportAgeDesc = prometheus.NewDesc("hoodaw_report_age_seconds",
+		"How long ago each report was written, left out for reports which can't be read.", []string{"report", "bucket"}, nil)
+	reportMaxAgeDesc = prometheus.NewDesc("hoodaw_report_max_age_seconds",
+		"How long after each report was written it is stale, from the report freshness config.", []string{"report", "bucket"}, nil)
+)
+
+func (c *reportAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- reportAgeDesc
+	ch <- reportMaxAgeDesc
+}
+
+func (c *reportAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	now := c.freshness.now()
+	for _, r := range c.freshness.Check(context.Background()).Reports {
+		ch <- prometheus.MustNewConstMetric(reportMaxAgeDesc, prometheus.GaugeValue, c.freshness.config.maxAge(r.Report).Seconds(), r.Report, r.Bucket)
+
+		lastModified, err := time.Parse(time.RFC3339, r.LastModified)
+		if err != nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(reportAgeDesc, prometheus.GaugeValue, now.Sub(lastModified).Seconds(), r.Report, r.Bucket)
+	}
+}
@@ -0,0 +1,139 @@
+package lib
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func testMetrics() *Metrics {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	return NewMetrics(&FreshnessChecker{
+		stat: func(ctx context.Context, bucket, key string) (time.Time, error) {
+			return now.Add(-time.Hour), nil
+		},
+		reports: map[string]reportObject{"hosted_services": {"hoodaw", "hosted_services.json"}},
+		config:  FreshnessConfig{DefaultMaxAgeHours: 26},
+		timeout: time.Second,
+		now:     func() time.Time { return now },
+	})
+}
+
+func TestMetrics_UpdateReports(t *testing.T) {
+	reports := fakeReports{
+		"hoodaw/hosted_services.json":  {`{"namespace_details": [{"Name": "ns-two", "Cluster": "live", "TeamName": "finance-dev"}]}`, "1"},
+		"hoodaw/live_one_domains.json": {`{"live_one_domains": [{"cluster": "live", "namespace": "ns-one", "hostname": "a.live-1"}, {"cluster": "live", "namespace": "ns-two", "hostname": "b.live-1"}]}`, "1"},
+		"hoodaw/helm_releases.json": {`{"clusters": [{"name": "live", "apps": [
+			{"name": "tracker-api", "installed_version": "1.0.0", "latest_version": "2.0.0"},
+			{"name": "payments", "installed_version": "1.1.0", "latest_version": "1.2.0"},
+			{"name": "up-to-date", "installed_version": "1.2.0", "latest_version": "1.2.0"}
+		]}]}`, "1"},
+		"hoodaw/namespace_costs.json":         {`{"namespace": {"ns-one": {"total": 3}, "ns-two": {"total": 12.5}}}`, "1"},
+		"apply-live/" + erroringNamespacesKey: {`[{"namespace": "ns-one"}]`, "1"},
+	}
+	data, _, _ := loadReports(reports.load, namespaceReports("hoodaw", "apply-live"))
+
+	m := testMetrics()
+	m.UpdateReports(data)
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"cost of a namespace", testutil.ToFloat64(m.namespaceCost.WithLabelValues("ns-two", "finance-dev")), 12.5},
+		{"major versions behind", testutil.ToFloat64(m.helmOutOfDate.WithLabelValues("live", "danger")), 1},
+		{"minor versions behind", testutil.ToFloat64(m.helmOutOfDate.WithLabelValues("live", "warning")), 1},
+		{"live-1 domains", testutil.ToFloat64(m.liveOneDomains), 2},
+		{"erroring namespaces", testutil.ToFloat64(m.erroringNamespaces), 1},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// a namespace which is gone from the report is gone from the metrics, and a report which
+	// couldn't be read leaves its metrics as they were
+	delete(reports, "hoodaw/live_one_domains.json")
+	reports["hoodaw/namespace_costs.json"] = struct {
+		data  string
+		stamp string
+	}{`{"namespace": {"ns-two": {"total": 12.5}}}`, "2"}
+	data, _, _ = loadReports(reports.load, namespaceReports("hoodaw", "apply-live"))
+	m.UpdateReports(data)
+
+	if got := testutil.CollectAndCount(m.namespaceCost); got != 1 {
+		t.Errorf("namespace costs = %d, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.liveOneDomains); got != 2 {
+		t.Errorf("live-1 domains = %v, want 2", got)
+	}
+
+	// a report which isn't valid json, such as one cut short, leaves its metrics as they were
+	// rather than publishing zeros, and is counted
+	m.UpdateReports(map[string][]byte{
+		"namespace_costs":     []byte(`{"namespace": {"ns-two": {"tot`),
+		"erroring_namespaces": []byte(`{"namespace": "ns-one"}`),
+	})
+
+	if got := testutil.ToFloat64(m.namespaceCost.WithLabelValues("ns-two", "finance-dev")); got != 12.5 {
+		t.Errorf("cost of a namespace after an invalid report = %v, want 12.5", got)
+	}
+	if got := testutil.ToFloat64(m.erroringNamespaces); got != 1 {
+		t.Errorf("erroring namespaces after an invalid report = %v, want 1", got)
+	}
+	for _, report := range []string{"namespace_costs", "erroring_namespaces"} {
+		if got := testutil.ToFloat64(m.reportErrors.WithLabelValues(report)); got != 1 {
+			t.Errorf("errors of %s = %v, want 1", report, got)
+		}
+	}
+}
+
+func TestMetrics_Instrument(t *testing.T) {
+	m := testMetrics()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /team/{team}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := m.Instrument(mux)
+
+	for _, path := range []string{"/team/webops", "/team/finance", "/nothing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(m.requests.WithLabelValues("GET /team/{team}", "GET", "404")); got != 2 {
+		t.Errorf("requests to the team route = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.requests.WithLabelValues("unmatched", "GET", "404")); got != 1 {
+		t.Errorf("unmatched requests = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.duration); got != 2 {
+		t.Errorf("request durations = %d routes, want 2", got)
+	}
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := testMetrics()
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`hoodaw_report_age_seconds{bucket="hoodaw",report="hosted_services.json"} 3600`,
+		`hoodaw_report_max_age_seconds{bucket="hoodaw",report="hosted_services.json"} 93600`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics don't contain %s", want)
+		}
+	}
+}
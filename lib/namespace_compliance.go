@@ -0,0 +1,88 @@
+package lib
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
+)
+
+type NamespaceCompliance struct {
+	Namespaces        []NonCompliantNamespace `json:"namespace_compliance"`
+	NamespacesChecked int                     `json:"namespaces_checked"`
+	LastUpdated       string
+	Total             int
+	Cluster           string
+	Clusters          []string
+	Listing           Listing
+}
+
+type NonCompliantNamespace struct {
+	hoodaw.NonCompliantNamespace
+	BusinessUnit string `json:"-"`
+}
+
+// nonCompliantNamespaceColumns are the columns the namespaces can be filtered and sorted by
+var nonCompliantNamespaceColumns = []column[NonCompliantNamespace]{
+	{name: "namespace", value: func(n NonCompliantNamespace) string { return n.Name }},
+	{name: "cluster", value: func(n NonCompliantNamespace) string { return n.Cluster }},
+	{name: "team", value: func(n NonCompliantNamespace) string { return n.TeamName }},
+	{name: "business_unit", value: func(n NonCompliantNamespace) string { return n.BusinessUnit }},
+	{name: "slack_channel", value: func(n NonCompliantNamespace) string { return n.TeamSlackChannel }},
+	{name: "issues", value: func(n NonCompliantNamespace) string { return strconv.Itoa(len(n.Issues)) }},
+}
+
+// NamespaceCompliancePage lists the namespaces with missing or invalid annotations, filtered,
+// sorted and paged by the query
+func NamespaceCompliancePage(w http.ResponseWriter, bucket string, query Query, format Format, client *s3.Client) error {
+	var compliance NamespaceCompliance
+	byteValue, filestamp, err := readReport(s3Load(client), bucket, "namespace_compliance.json", &compliance)
+	if err != nil {
+		return err
+	}
+
+	compliance.LastUpdated = filestamp
+	compliance.Cluster = query.Filters["cluster"]
+
+	// the business unit is looked up for namespaces whose own annotation is missing or invalid
+	owners := loadNamespaceOwners(client, bucket)
+
+	clusters := make([]string, 0)
+	for i, ns := range compliance.Namespaces {
+		clusters = append(clusters, ns.Cluster)
+		compliance.Namespaces[i].BusinessUnit = owners.lookup(ns.Cluster, ns.Name).BusinessUnit
+	}
+	compliance.Clusters = filterOptions(clusters)
+
+	matching, err := queryRows(query, compliance.Namespaces, nonCompliantNamespaceColumns)
+	if err != nil {
+		return err
+	}
+	compliance.Namespaces, compliance.Listing = paginate(query, matching)
+
+	if format != FormatHTML {
+		return writeRows(w, format, "namespace_compliance", byteValue, "namespace_compliance", compliance.Namespaces, compliance.Listing, namespaceComplianceCSV)
+	}
+
+	compliance.Total = len(matching)
+
+	return render(w, http.StatusOK, "namespace_compliance.html", compliance)
+}
+
+// namespaceComplianceCSV is a row for each non-compliant namespace, with its issues in one cell
+func namespaceComplianceCSV(data []byte) (csvTable, error) {
+	var compliance NamespaceCompliance
+	if err := json.Unmarshal(data, &compliance); err != nil {
+		return csvTable{}, err
+	}
+
+	table := csvTable{header: []string{"cluster", "namespace", "team", "slack_channel", "issues"}}
+	for _, n := range compliance.Namespaces {
+		table.rows = append(table.rows, []string{n.Cluster, n.Name, n.TeamName, n.TeamSlackChannel, strings.Join(n.Issues, "; ")})
+	}
+
+	return table, nil
+}
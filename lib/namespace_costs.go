@@ -2,51 +2,111 @@ package lib
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
-	"text/template"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
 )
 
-type Breakdown struct{}
+type NamespaceCost = hoodaw.NamespaceCost
 
-type NamespaceCost struct {
-	Breakdown map[string]float32 `json:"breakdown"`
-	Total     float32
+// NamespaceCostRow is the cost of a namespace as a row of the page
+type NamespaceCostRow struct {
+	Namespace    string
+	Team         string
+	BusinessUnit string
+	NamespaceCost
+}
+
+// namespaceCostEntry is the cost of a namespace in the data formats, which list the namespaces in
+// the order of the page
+type namespaceCostEntry struct {
+	Namespace string `json:"namespace"`
+	NamespaceCost
 }
 
 type Costs struct {
-	Namespaces  map[string]NamespaceCost `json:"namespace"`
+	hoodaw.Costs
 	LastUpdated string
 	Total       float32
+	Cluster     string
+	Clusters    []string
+	Rows        []NamespaceCostRow
+	Listing     Listing
 }
 
-func NamespaceCostsPage(w http.ResponseWriter, bucket string, wantJson bool, client *s3.Client) {
-	t := template.Must(template.ParseFiles("lib/templates/namespace_costs.html"))
+// namespaceCostColumns are the columns the namespace costs can be filtered and sorted by
+var namespaceCostColumns = []column[NamespaceCostRow]{
+	{name: "namespace", value: func(r NamespaceCostRow) string { return r.Namespace }},
+	{name: "team", value: func(r NamespaceCostRow) string { return r.Team }},
+	{name: "business_unit", value: func(r NamespaceCostRow) string { return r.BusinessUnit }},
+	{name: "cluster", value: func(r NamespaceCostRow) string { return joinCSV(r.Clusters) }, values: func(r NamespaceCostRow) []string { return r.Clusters }},
+	{name: "total", value: func(r NamespaceCostRow) string { return formatFloat(float64(r.Total)) }},
+}
 
-	byteValue, filestamp, err := utils.ImportS3File(client, bucket, "namespace_costs.json")
+// NamespaceCostsPage lists the cost of each namespace, filtered, sorted and paged by the query.
+func NamespaceCostsPage(w http.ResponseWriter, bucket string, query Query, format Format, client *s3.Client) error {
+	var namespaceCosts Costs
+	byteValue, filestamp, err := readReport(s3Load(client), bucket, "namespace_costs.json", &namespaceCosts)
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
 
-	if wantJson {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(byteValue)
-		return
+	namespaceCosts.LastUpdated = filestamp
+	namespaceCosts.Cluster = query.Filters["cluster"]
+
+	// the costs aren't tagged by cluster, so the owner is of the namespace in any cluster
+	owners := loadNamespaceOwners(client, bucket)
+
+	clusters := make([]string, 0)
+	rows := make([]NamespaceCostRow, 0, len(namespaceCosts.Namespaces))
+	for _, name := range sortedKeys(namespaceCosts.Namespaces) {
+		ns := namespaceCosts.Namespaces[name]
+		clusters = append(clusters, ns.Clusters...)
+		owner := owners.lookup("", name)
+		rows = append(rows, NamespaceCostRow{Namespace: name, Team: owner.TeamName, BusinessUnit: owner.BusinessUnit, NamespaceCost: ns})
 	}
+	namespaceCosts.Clusters = filterOptions(clusters)
 
-	var namespaceCosts Costs
-	json.Unmarshal(byteValue, &namespaceCosts)
+	matching, err := queryRows(query, rows, namespaceCostColumns)
+	if err != nil {
+		return err
+	}
+	namespaceCosts.Rows, namespaceCosts.Listing = paginate(query, matching)
 
-	namespaceCosts.LastUpdated = filestamp
+	if format != FormatHTML {
+		page := make([]namespaceCostEntry, 0, len(namespaceCosts.Rows))
+		for _, r := range namespaceCosts.Rows {
+			page = append(page, namespaceCostEntry{Namespace: r.Namespace, NamespaceCost: r.NamespaceCost})
+		}
+
+		return writeRows(w, format, "namespace_costs", byteValue, "namespace", page, namespaceCosts.Listing, namespaceCostsCSV)
+	}
 
-	for _, ns := range namespaceCosts.Namespaces {
-		namespaceCosts.Total += ns.Total
+	for _, r := range matching {
+		namespaceCosts.Total += r.Total
 	}
 
-	if err := t.ExecuteTemplate(w, "namespace_costs.html", namespaceCosts); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	return render(w, http.StatusOK, "namespace_costs.html", namespaceCosts)
+}
+
+// namespaceCostsCSV is a row for each resource a namespace is charged for, with a total row for
+// each namespace, in the order of the page
+func namespaceCostsCSV(data []byte) (csvTable, error) {
+	var costs struct {
+		Namespaces []namespaceCostEntry `json:"namespace"`
 	}
+	if err := json.Unmarshal(data, &costs); err != nil {
+		return csvTable{}, err
+	}
+
+	table := csvTable{header: []string{"namespace", "clusters", "resource", "cost"}}
+	for _, c := range costs.Namespaces {
+		for _, resource := range sortedKeys(c.Breakdown) {
+			table.rows = append(table.rows, []string{c.Namespace, joinCSV(c.Clusters), resource, formatFloat(float64(c.Breakdown[resource]))})
+		}
+		table.rows = append(table.rows, []string{c.Namespace, joinCSV(c.Clusters), "total", formatFloat(float64(c.Total))})
+	}
+
+	return table, nil
 }
@@ -2,56 +2,18 @@ package lib
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"text/template"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
 	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
 )
 
-type NamespaceCosts struct {
-	Namespace map[string]struct {
-		Breakdown map[string]float32 `json:"breakdown"`
-		Total     float32            `json:"total"`
-	} `json:"namespace"`
-	LastUpdated string `json:"last_updated"`
-}
-
-type NamespaceUsage struct {
-	Data []struct {
-		Requested struct {
-			CPU    int `json:"CPU"`
-			Memory int `json:"Memory"`
-			Pods   int `json:"Pods"`
-		} `json:"Requested"`
-		Used struct {
-			CPU    int `json:"CPU"`
-			Memory int `json:"Memory"`
-			Pods   int `json:"Pods"`
-		} `json:"Used"`
-		Hardlimits struct {
-			CPU    int `json:"CPU"`
-			Memory int `json:"Memory"`
-			Pods   int `json:"Pods"`
-		} `json:"Hardlimits"`
-		ContainerCount int    `json:"ContainerCount"`
-		Name           string `json:"Name"`
-	} `json:"data"`
-	LastUpdated string `json:"updated_at"`
-}
-
-type Tags struct {
-	Data []struct {
-		Namespace    string   `json:"Name"`
-		Application  string   `json:"Application"`
-		BusinessUnit string   `json:"BusinessUnit"`
-		TeamName     string   `json:"TeamName"`
-		SlackChannel string   `json:"TeamSlackChannel"`
-		SourceCode   string   `json:"GithubURL"`
-		DomainNames  []string `json:"DomainNames"`
-	} `json:"namespace_details"`
-}
+type NamespaceUsage = hoodaw.NamespaceUsage
 
 type Usage struct {
 	Namespace string
@@ -76,6 +38,8 @@ type Usage struct {
 	Name           string
 	ContainerCount int
 	LastUpdated    string
+	Cluster        string
+	Clusters       []string
 	Tags           struct {
 		Application  string
 		BusinessUnit string
@@ -84,47 +48,99 @@ type Usage struct {
 		SourceCode   string
 		DomainNames  []string
 	}
+	HelmReleases       []HelmRelease
+	Domains            []Domain
+	ErroringNamespaces []NamespaceError
+}
+
+// NamespaceUsagePage shows the costs, usage, annotations, helm releases, domains to migrate and
+// apply errors of a namespace. A namespace name can be used in more than one cluster, the first of
+// which is shown unless cluster is set.
+func NamespaceUsagePage(w http.ResponseWriter, bucket, errorNsBucket, namespace, cluster string, format Format, client *s3.Client) error {
+	return namespacePage(w, s3Load(client), namespaceReports(bucket, errorNsBucket), namespace, cluster, format)
 }
 
-func NamespaceUsagePage(w http.ResponseWriter, bucket, namespace string, wantJson bool, client *s3.Client) {
-	t := template.Must(template.ParseFiles("lib/templates/namespaces.html"))
+// namespacePage shows the namespace from the reports read by load, or a 404 suggesting the
+// namespaces with similar names when none of the reports has it. The hosted services report has to
+// be read for a namespace to be not found, the others are left out when they can't be.
+func namespacePage(w http.ResponseWriter, load loadFunc, reports map[string]reportObject, namespace, cluster string, format Format) error {
+	data, stamps, errs := loadReports(load, reports)
+	if err, ok := errs["hosted_services"]; ok {
+		return err
+	}
+	logReportErrors(errs)
 
-	byteValue, filestamp, err := utils.ImportS3File(client, bucket, "namespace_costs.json")
+	usage, ok, err := buildUsage(namespace, cluster, data)
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
+	if !ok {
+		return namespaceNotFound(w, namespace, data, format)
+	}
+	usage.LastUpdated = stamps["namespace_usage"]
 
-	var namespaceCosts NamespaceCosts
-	json.Unmarshal(byteValue, &namespaceCosts)
+	if format != FormatHTML {
+		data, err := json.Marshal(usage)
+		if err != nil {
+			return err
+		}
+		return writeReport(w, format, namespace, data, namespaceUsageCSV)
+	}
 
-	byteValue, filestamp, err = utils.ImportS3File(client, bucket, "namespace_usage.json")
-	if err != nil {
-		fmt.Println(err)
+	return render(w, http.StatusOK, "namespaces.html", usage)
+}
+
+// buildUsage merges the results of the reports, by name, for the namespace in the cluster, and
+// reports whether any of them has the namespace. It fails when a report isn't valid json.
+func buildUsage(namespace, cluster string, data map[string][]byte) (Usage, bool, error) {
+	var namespaceCosts Costs
+	if err := decodeReport(data, "namespace_costs", &namespaceCosts); err != nil {
+		return Usage{}, false, err
 	}
 
 	var namespaceUsage NamespaceUsage
-	json.Unmarshal(byteValue, &namespaceUsage)
-	namespaceUsage.LastUpdated = filestamp
-
-	byteValue, filestamp, err = utils.ImportS3File(client, bucket, "hosted_services.json")
-	if err != nil {
-		fmt.Println(err)
+	if err := decodeReport(data, "namespace_usage", &namespaceUsage); err != nil {
+		return Usage{}, false, err
 	}
 
-	var tags Tags
-	json.Unmarshal(byteValue, &tags)
+	var hostedServices HostedServices
+	if err := decodeReport(data, "hosted_services", &hostedServices); err != nil {
+		return Usage{}, false, err
+	}
 
-	var usage Usage
-	for ns, v := range namespaceCosts.Namespace {
-		if ns == namespace {
-			usage.Namespace = ns
-			usage.Breakdown = v.Breakdown
-			usage.Total = v.Total
+	clusters := make([]string, 0)
+	for _, v := range namespaceUsage.Namespaces {
+		if v.Name == namespace {
+			clusters = append(clusters, v.Cluster)
+		}
+	}
+	for _, v := range hostedServices.HostedServices {
+		if v.Namespace == namespace {
+			clusters = append(clusters, v.Cluster)
 		}
 	}
 
-	for _, v := range namespaceUsage.Data {
-		if v.Name == namespace {
+	cost, costed := namespaceCosts.Namespaces[namespace]
+	if len(clusters) == 0 && !costed {
+		return Usage{}, false, nil
+	}
+
+	usage := Usage{
+		Namespace:          namespace,
+		Breakdown:          cost.Breakdown,
+		Total:              cost.Total,
+		Clusters:           filterOptions(clusters),
+		HelmReleases:       make([]HelmRelease, 0),
+		Domains:            make([]Domain, 0),
+		ErroringNamespaces: make([]NamespaceError, 0),
+	}
+	if cluster == "" && len(usage.Clusters) > 0 {
+		cluster = usage.Clusters[0]
+	}
+	usage.Cluster = cluster
+
+	for _, v := range namespaceUsage.Namespaces {
+		if v.Name == namespace && matchesFilter(cluster, v.Cluster) {
 			usage.CPU.Requested = v.Requested.CPU
 			usage.CPU.Used = v.Used.CPU
 			usage.CPU.HardLimits = v.Hardlimits.CPU
@@ -139,12 +155,11 @@ func NamespaceUsagePage(w http.ResponseWriter, bucket, namespace string, wantJso
 
 			usage.ContainerCount = v.ContainerCount
 			usage.Name = v.Name
-			usage.LastUpdated = namespaceUsage.LastUpdated
 		}
 	}
 
-	for _, v := range tags.Data {
-		if v.Namespace == namespace {
+	for _, v := range hostedServices.HostedServices {
+		if v.Namespace == namespace && matchesFilter(cluster, v.Cluster) {
 			usage.Tags.Application = v.Application
 			usage.Tags.BusinessUnit = v.BusinessUnit
 			usage.Tags.TeamName = v.TeamName
@@ -154,13 +169,94 @@ func NamespaceUsagePage(w http.ResponseWriter, bucket, namespace string, wantJso
 		}
 	}
 
-	if wantJson {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(byteValue)
-		return
+	var helmReleases HelmReleases
+	if err := decodeReport(data, "helm_releases", &helmReleases); err != nil {
+		return Usage{}, false, err
 	}
+	for _, c := range helmReleases.Clusters {
+		if !matchesFilter(cluster, c.ClusterName) {
+			continue
+		}
+		for _, h := range c.HelmReleases {
+			if h.Namespace == namespace {
+				h.State = utils.CompareVersions(h.InstalledVersion, h.LatestVersion)
+				usage.HelmReleases = append(usage.HelmReleases, h)
+			}
+		}
+	}
+
+	var domains Domains
+	if err := decodeReport(data, "live_one_domains", &domains); err != nil {
+		return Usage{}, false, err
+	}
+	for _, d := range domains.Data {
+		if d.Namespace == namespace && matchesFilter(cluster, d.Cluster) {
+			usage.Domains = append(usage.Domains, d)
+		}
+	}
+
+	// apply-live only applies the namespaces of the live cluster
+	var erroringNamespaces []NamespaceError
+	if err := decodeReport(data, "erroring_namespaces", &erroringNamespaces); err != nil {
+		return Usage{}, false, err
+	}
+	for _, e := range erroringNamespaces {
+		if e.Namespace == namespace && matchesFilter(cluster, "live") {
+			usage.ErroringNamespaces = append(usage.ErroringNamespaces, e)
+		}
+	}
+
+	return usage, true, nil
+}
+
+// NamespaceNotFound is the page of a namespace none of the reports has
+type NamespaceNotFound struct {
+	Namespace string
+	Similar   []string
+}
+
+// namespaceNotFound is a 404 with the namespaces whose names contain, or are contained by, the
+// name asked for
+func namespaceNotFound(w http.ResponseWriter, namespace string, data map[string][]byte, format Format) error {
+	var hostedServices HostedServices
+	if err := decodeReport(data, "hosted_services", &hostedServices); err != nil {
+		return err
+	}
+
+	similar := make([]string, 0)
+	name := strings.ToLower(namespace)
+	for _, v := range hostedServices.HostedServices {
+		other := strings.ToLower(v.Namespace)
+		if name != "" && (strings.Contains(other, name) || strings.Contains(name, other)) {
+			similar = append(similar, v.Namespace)
+		}
+	}
+	notFound := NamespaceNotFound{Namespace: namespace, Similar: filterOptions(similar)}
 
-	if err := t.ExecuteTemplate(w, "namespaces.html", usage); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if format != FormatHTML {
+		msg := fmt.Sprintf("no report has a namespace called %q", namespace)
+		if len(notFound.Similar) > 0 {
+			msg += fmt.Sprintf(", did you mean %s?", strings.Join(notFound.Similar, ", "))
+		}
+		return &StatusError{Status: http.StatusNotFound, Err: errors.New(msg)}
 	}
+
+	return render(w, http.StatusNotFound, "namespace_not_found.html", notFound)
+}
+
+// namespaceUsageCSV is a single row of the costs, usage and annotations of a namespace
+func namespaceUsageCSV(data []byte) (csvTable, error) {
+	var usage Usage
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return csvTable{}, err
+	}
+
+	return csvTable{
+		header: []string{"cluster", "namespace", "application", "business_unit", "team", "slack_channel", "source_code", "domain_names", "total_cost",
+			"cpu_requested", "cpu_used", "cpu_hard_limits", "memory_requested", "memory_used", "memory_hard_limits", "pods_requested", "pods_used", "pods_hard_limits", "containers"},
+		rows: [][]string{{usage.Cluster, usage.Namespace, usage.Tags.Application, usage.Tags.BusinessUnit, usage.Tags.TeamName, usage.Tags.SlackChannel, usage.Tags.SourceCode, joinCSV(usage.Tags.DomainNames), formatFloat(float64(usage.Total)),
+			strconv.Itoa(usage.CPU.Requested), strconv.Itoa(usage.CPU.Used), strconv.Itoa(usage.CPU.HardLimits),
+			strconv.Itoa(usage.Memory.Requested), strconv.Itoa(usage.Memory.Used), strconv.Itoa(usage.Memory.HardLimits),
+			strconv.Itoa(usage.Pods.Requested), strconv.Itoa(usage.Pods.Used), strconv.Itoa(usage.Pods.HardLimits), strconv.Itoa(usage.ContainerCount)}},
+	}, nil
 }
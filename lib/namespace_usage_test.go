@@ -0,0 +1,130 @@
+package lib
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func namespaceFakeReports() fakeReports {
+	return fakeReports{
+		"hoodaw/hosted_services.json": {`{"namespace_details": [
+			{"Name": "ns-one", "Cluster": "live", "Application": "Tracker", "TeamName": "webops"},
+			{"Name": "ns-one", "Cluster": "live-2", "Application": "Tracker v2", "TeamName": "webops"},
+			{"Name": "ns-two", "Cluster": "live", "TeamName": "finance-dev"}
+		]}`, "1"},
+		"hoodaw/namespace_costs.json":         {`{"namespace": {"ns-one": {"breakdown": {"rds": 10}, "total": 12.5}}}`, "1"},
+		"hoodaw/namespace_usage.json":         {`{"data": [{"Name": "ns-one", "Cluster": "live", "ContainerCount": 3, "Used": {"CPU": 20, "Memory": 128, "Pods": 2}}]}`, "2024-01-02 10:04:05"},
+		"hoodaw/helm_releases.json":           {`{"clusters": [{"name": "live", "apps": [{"name": "tracker", "namespace": "ns-one", "installed_version": "1.0.0", "latest_version": "2.0.0"}, {"name": "other", "namespace": "ns-two"}]}, {"name": "live-2", "apps": [{"name": "tracker-2", "namespace": "ns-one"}]}]}`, "1"},
+		"hoodaw/live_one_domains.json":        {`{"live_one_domains": [{"cluster": "live", "namespace": "ns-one", "hostname": "tracker.apps.live-1.cloud-platform.service.justice.gov.uk"}]}`, "1"},
+		"apply-live/" + erroringNamespacesKey: {`[{"namespace": "ns-one", "error": "timeout", "build_id": 42}]`, "1"},
+	}
+}
+
+func Test_namespacePage(t *testing.T) {
+	reports := namespaceFakeReports()
+
+	w := httptest.NewRecorder()
+	if err := namespacePage(w, reports.load, namespaceReports("hoodaw", "apply-live"), "ns-one", "", FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("namespacePage() status = %d, want 200", w.Code)
+	}
+
+	var usage Usage
+	if err := json.Unmarshal(w.Body.Bytes(), &usage); err != nil {
+		t.Fatalf("namespacePage() = %s, want the json of the namespace: %v", w.Body, err)
+	}
+	if usage.Namespace != "ns-one" || usage.Cluster != "live" || usage.Total != 12.5 || usage.Breakdown["rds"] != 10 {
+		t.Errorf("namespacePage() costs = %+v, want the namespace in the first of its clusters", usage)
+	}
+	if usage.CPU.Used != 20 || usage.ContainerCount != 3 || usage.LastUpdated != "2024-01-02 10:04:05" {
+		t.Errorf("namespacePage() usage = %+v, want the usage of the namespace", usage)
+	}
+	if usage.Tags.Application != "Tracker" || usage.Tags.TeamName != "webops" {
+		t.Errorf("namespacePage() tags = %+v, want the annotations of the namespace in live", usage.Tags)
+	}
+	if len(usage.HelmReleases) != 1 || usage.HelmReleases[0].Name != "tracker" || usage.HelmReleases[0].State != "danger" {
+		t.Errorf("namespacePage() helm releases = %+v, want the release of the namespace in live", usage.HelmReleases)
+	}
+	if len(usage.Domains) != 1 || len(usage.ErroringNamespaces) != 1 {
+		t.Errorf("namespacePage() = %d domains and %d errors, want 1 of each", len(usage.Domains), len(usage.ErroringNamespaces))
+	}
+	if strings.Contains(w.Body.String(), "namespace_details") {
+		t.Error("namespacePage() returned the hosted services report")
+	}
+
+	w = httptest.NewRecorder()
+	if err := namespacePage(w, reports.load, namespaceReports("hoodaw", "apply-live"), "ns-one", "live-2", FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal(w.Body.Bytes(), &usage)
+	if usage.Tags.Application != "Tracker v2" || len(usage.HelmReleases) != 1 || len(usage.Domains) != 0 || len(usage.ErroringNamespaces) != 0 {
+		t.Errorf("namespacePage() in live-2 = %+v, want only the results of live-2", usage)
+	}
+}
+
+func Test_namespacePage_notFound(t *testing.T) {
+	withoutHostedServices := namespaceFakeReports()
+	delete(withoutHostedServices, "hoodaw/hosted_services.json")
+	invalidHelmReleases := namespaceFakeReports()
+	invalidHelmReleases["hoodaw/helm_releases.json"] = struct {
+		data  string
+		stamp string
+	}{`{"clusters": [`, "1"}
+
+	tests := []struct {
+		name      string
+		reports   fakeReports
+		namespace string
+		want      ErrorBody
+	}{
+		{
+			"unknown",
+			namespaceFakeReports(),
+			"nothing-here",
+			ErrorBody{Status: 404, Error: "Not Found", Message: `no report has a namespace called "nothing-here"`},
+		},
+		{
+			"similar names",
+			namespaceFakeReports(),
+			"ns",
+			ErrorBody{Status: 404, Error: "Not Found", Message: `no report has a namespace called "ns", did you mean ns-one, ns-two?`},
+		},
+		{
+			"without the namespaces",
+			withoutHostedServices,
+			"ns-one",
+			ErrorBody{Status: 500, Error: "Internal Server Error", Message: "Unable to read the hosted_services.json report", Report: "hosted_services.json"},
+		},
+		{
+			"invalid report",
+			invalidHelmReleases,
+			"ns-one",
+			ErrorBody{Status: 500, Error: "Internal Server Error", Message: "The helm_releases.json report isn't valid json", Report: "helm_releases.json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/namespace/"+tt.namespace, nil)
+			if err := namespacePage(w, tt.reports.load, namespaceReports("hoodaw", "apply-live"), tt.namespace, "", FormatJSON); err != nil {
+				WriteError(w, r, FormatJSON, err)
+			}
+
+			if w.Code != tt.want.Status {
+				t.Errorf("namespacePage() status = %d, want %d", w.Code, tt.want.Status)
+			}
+			var got ErrorBody
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("namespacePage() = %s, want an error body: %v", w.Body, err)
+			}
+			if got != tt.want {
+				t.Errorf("namespacePage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package lib
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// namespaceOwners holds the hosted service of each namespace, keyed on both cluster/namespace
+// and namespace, to look up the team which owns the results of other reports
+type namespaceOwners map[string]HostedService
+
+// ownersTTL is how long the namespace owners are kept before the hosted services report is read
+// again, as each page of namespaces looks up their owners
+const ownersTTL = time.Minute
+
+// ownersCache is the namespace owners read from the hosted services report of each bucket
+type ownersCache struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	entries map[string]ownersEntry
+}
+
+type ownersEntry struct {
+	owners namespaceOwners
+	read   time.Time
+}
+
+// namespaceOwnersCache is the namespace owners read by the pages so far
+var namespaceOwnersCache = &ownersCache{now: time.Now, entries: make(map[string]ownersEntry)}
+
+// loadNamespaceOwners returns the namespace owners from the hosted services report, read at most
+// once every ownersTTL. Pages still render when it can't be read, their results just have no
+// owning team.
+func loadNamespaceOwners(client *s3.Client, bucket string) namespaceOwners {
+	return namespaceOwnersCache.get(s3Load(client), bucket)
+}
+
+// get returns the owners of the bucket when they were read within the ownersTTL, or else reads
+// them with load. Owners which couldn't be read aren't kept, so the next page reads them again.
+func (c *ownersCache) get(load loadFunc, bucket string) namespaceOwners {
+	c.mu.Lock()
+	e, ok := c.entries[bucket]
+	c.mu.Unlock()
+
+	if ok && c.now().Sub(e.read) < ownersTTL {
+		return e.owners
+	}
+
+	owners, ok := readNamespaceOwners(load, bucket)
+	if ok {
+		c.mu.Lock()
+		c.entries[bucket] = ownersEntry{owners: owners, read: c.now()}
+		c.mu.Unlock()
+	}
+
+	return owners
+}
+
+// readNamespaceOwners reads the namespace owners with load, reporting whether they could be read.
+// The error of a hosted services report which can't be read or isn't valid json is logged and
+// counted.
+func readNamespaceOwners(load loadFunc, bucket string) (namespaceOwners, bool) {
+	const key = "hosted_services.json"
+
+	byteValue, _, err := load(bucket, key)
+	if err != nil {
+		slog.Warn("showing page without owners", "report", key, "error", err)
+		return make(namespaceOwners), false
+	}
+
+	var hostedServices HostedServices
+	if err := json.Unmarshal(byteValue, &hostedServices); err != nil {
+		countFetchError(key, err)
+		slog.Warn("showing page without owners", "report", key, "error", err)
+		return make(namespaceOwners), false
+	}
+
+	return newNamespaceOwners(hostedServices.HostedServices), true
+}
+
+func newNamespaceOwners(hostedServices []HostedService) namespaceOwners {
+	owners := make(namespaceOwners)
+
+	for _, hs := range hostedServices {
+		owners[hs.Cluster+"/"+hs.Namespace] = hs
+		if _, ok := owners[hs.Namespace]; !ok {
+			owners[hs.Namespace] = hs
+		}
+	}
+
+	return owners
+}
+
+// lookup returns the hosted service of the namespace in cluster, falling back to a namespace
+// of the same name in any cluster for reports which aren't tagged by cluster
+func (o namespaceOwners) lookup(cluster, namespace string) HostedService {
+	if hs, ok := o[cluster+"/"+namespace]; ok {
+		return hs
+	}
+
+	return o[namespace]
+}
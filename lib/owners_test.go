@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_namespaceOwners_lookup(t *testing.T) {
+	owners := newNamespaceOwners([]HostedService{
+		{Namespace: "app", Cluster: "live", TeamName: "live team"},
+		{Namespace: "app", Cluster: "manager", TeamName: "manager team"},
+		{Namespace: "untagged", TeamName: "untagged team"},
+	})
+
+	tests := []struct {
+		name      string
+		cluster   string
+		namespace string
+		want      string
+	}{
+		{"namespace in cluster", "manager", "app", "manager team"},
+		{"result without a cluster", "", "app", "live team"},
+		{"hosted services without a cluster", "live", "untagged", "untagged team"},
+		{"unknown namespace", "live", "missing", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := owners.lookup(tt.cluster, tt.namespace).TeamName; got != tt.want {
+				t.Errorf("namespaceOwners.lookup() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readNamespaceOwners(t *testing.T) {
+	reports := fakeReports{
+		"hoodaw/hosted_services.json":  {`{"namespace_details": [{"Name": "app", "Cluster": "live", "TeamName": "live team"}]}`, "1"},
+		"invalid/hosted_services.json": {`{"namespace_details": [`, "1"},
+	}
+
+	if got, ok := readNamespaceOwners(reports.load, "hoodaw"); !ok || got.lookup("live", "app").TeamName != "live team" {
+		t.Errorf("readNamespaceOwners() team = %q, want live team", got.lookup("live", "app").TeamName)
+	}
+	if got, ok := readNamespaceOwners(reports.load, "missing"); ok || len(got) != 0 {
+		t.Errorf("readNamespaceOwners() of a missing report = %v, want no owners", got)
+	}
+
+	invalid := storeFetchErrors.WithLabelValues("hosted_services.json", "500")
+	before := testutil.ToFloat64(invalid)
+	if got, ok := readNamespaceOwners(reports.load, "invalid"); ok || len(got) != 0 {
+		t.Errorf("readNamespaceOwners() of an invalid report = %v, want no owners", got)
+	}
+	if got := testutil.ToFloat64(invalid) - before; got != 1 {
+		t.Errorf("readNamespaceOwners() of an invalid report counted %v fetch errors, want 1", got)
+	}
+}
+
+func Test_ownersCache_get(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	reports := fakeReports{"hoodaw/hosted_services.json": {`{"namespace_details": [{"Name": "app", "Cluster": "live", "TeamName": "webops"}]}`, "1"}}
+	reads := 0
+	load := func(bucket, key string) ([]byte, string, error) {
+		reads++
+		return reports.load(bucket, key)
+	}
+	c := &ownersCache{now: func() time.Time { return now }, entries: make(map[string]ownersEntry)}
+
+	c.get(load, "hoodaw")
+	now = now.Add(ownersTTL - time.Second)
+	if got := c.get(load, "hoodaw").lookup("live", "app").TeamName; got != "webops" || reads != 1 {
+		t.Errorf("get() within the TTL = %q after %d reads, want webops after 1", got, reads)
+	}
+
+	reports["hoodaw/hosted_services.json"] = struct {
+		data  string
+		stamp string
+	}{`{"namespace_details": [{"Name": "app", "Cluster": "live", "TeamName": "analytics"}]}`, "2"}
+	now = now.Add(time.Second)
+	if got := c.get(load, "hoodaw").lookup("live", "app").TeamName; got != "analytics" || reads != 2 {
+		t.Errorf("get() after the TTL = %q after %d reads, want analytics after 2", got, reads)
+	}
+
+	// owners which couldn't be read are read again by the next page
+	c.get(load, "missing")
+	c.get(load, "missing")
+	if reads != 4 {
+		t.Errorf("get() of a missing report made %d reads, want 4", reads)
+	}
+}
@@ -0,0 +1,60 @@
+package lib
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+)
+
+// templateFiles are the templates of the pages, built into the binary
+//
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// layoutTemplates are the templates every page is parsed with: the layout with the header and
+// nav, which renders the title, content and scripts the page defines, and the partials
+var layoutTemplates = []string{"templates/layout.html", "templates/pagination.html"}
+
+// pages are the templates of each page by file name, parsed once
+var pages = parsePages()
+
+func parsePages() map[string]*template.Template {
+	files, err := fs.Glob(templateFiles, "templates/*.html")
+	if err != nil {
+		panic(err)
+	}
+
+	pages := make(map[string]*template.Template)
+	for _, f := range files {
+		if f == layoutTemplates[0] || f == layoutTemplates[1] {
+			continue
+		}
+		pages[path.Base(f)] = template.Must(template.ParseFS(templateFiles, append(layoutTemplates, f)...))
+	}
+
+	return pages
+}
+
+// render writes the page with the data and the status. The page is rendered before anything is
+// written so a page which fails to render is an error rather than half a page.
+func render(w http.ResponseWriter, status int, name string, data any) error {
+	t, ok := pages[name]
+	if !ok {
+		return fmt.Errorf("no page called %s", name)
+	}
+
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "layout.html", data); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, err := buf.WriteTo(w)
+
+	return err
+}
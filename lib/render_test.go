@@ -0,0 +1,150 @@
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
+)
+
+// renderFixtures are the data of each page, and what the page should show of it
+func renderFixtures(t *testing.T) map[string]struct {
+	data any
+	want []string
+} {
+	q, err := ParseQuery(url.Values{"per_page": {"1"}, "cluster": {"live"}}, FormatHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, listing := paginate(q, []int{1, 2})
+
+	namespaceError := NamespaceError{NamespaceError: hoodaw.NamespaceError{Namespace: "ns-one", Error: "timeout <b>applying</b>", BuildID: 42}, BuildURL: "https://ci/builds/42", Team: "webops"}
+	domain := Domain{Domain: hoodaw.Domain{Cluster: "live", Namespace: "ns-one", IngressName: "tracker", URL: "tracker.apps.live-1.cloud-platform.service.justice.gov.uk"}, Team: "webops"}
+	release := HelmRelease{HelmRelease: hoodaw.HelmRelease{Name: "tracker-api", Namespace: "ns-one", Chart: "api-1.0.0", InstalledVersion: "1.0.0", LatestVersion: "2.0.0"}, State: "danger"}
+
+	usage := Usage{Namespace: "ns-one", Cluster: "live", Clusters: []string{"live", "live-2"}, Total: 12.5, Breakdown: map[string]float32{"rds": 10}, Name: "ns-one"}
+	usage.Tags.TeamName = "webops"
+	usage.HelmReleases, usage.Domains, usage.ErroringNamespaces = []HelmRelease{release}, []Domain{domain}, []NamespaceError{namespaceError}
+
+	return map[string]struct {
+		data any
+		want []string
+	}{
+		"certificate_expiry.html": {
+			CertificateExpiry{Certificates: []Certificate{{reportCertificate: hoodaw.Certificate{Cluster: "live", Namespace: "ns-one", Host: "tracker.service.justice.gov.uk"}, DaysLeft: 3, Team: "webops"}}, Total: 3, Expired: 1, Expiring: 1, Clusters: []string{"live"}, Listing: listing},
+			[]string{"tracker.service.justice.gov.uk", `href="?cluster=live&amp;page=2&amp;per_page=1"`},
+		},
+		"error.html": {
+			ErrorBody{Status: 404, Error: "Not Found", Message: "The hosted_services.json report hasn't been written yet", Report: "hosted_services.json"},
+			[]string{"404 Not Found", "hasn&#39;t been written yet"},
+		},
+		"erroring_namespaces.html": {
+			ErroringNamespaces{
+				Namespaces: []NamespaceError{namespaceError},
+				Groups:     []ErrorGroup{{Signature: "timeout <b>applying</b>", Namespaces: []NamespaceError{namespaceError}}},
+				History:    []NamespaceHistory{{Namespace: "ns-one", Team: "webops", Runs: []*NamespaceError{&namespaceError, nil}}},
+				Runs:       []string{"2024-01-02 10:04", "2024-01-01 10:04"},
+				Trends:     map[string]ErrorTrend{"ns-one": {ErrorTrend: hoodaw.ErrorTrend{Namespace: "ns-one", Status: "new", FirstSeen: "2024-01-02"}}},
+				Total:      1,
+				Listing:    listing,
+			},
+			[]string{"ns-one", "timeout &lt;b&gt;applying&lt;/b&gt;", "https://ci/builds/42"},
+		},
+		"freshness.html": {
+			Freshness{Reports: []ReportStatus{{Report: "hosted_services.json", Bucket: "hoodaw", Status: ReportStale, Age: "30h0m0s", MaxAge: "26h0m0s"}, {Report: "namespace_usage.json", Bucket: "hoodaw", Status: ReportMissing, Error: "<no such key>"}}, Stale: 2, Listing: listing},
+			[]string{"hosted_services.json", "30h0m0s", `title="&lt;no such key&gt;"`},
+		},
+		"helm_releases.html": {
+			HelmReleases{Clusters: []Cluster{{ClusterName: "live", HelmReleases: []HelmRelease{release}}}, ClusterList: []string{"live"}, States: []string{"danger"}, Listing: listing},
+			[]string{"tracker-api", "2.0.0"},
+		},
+		"hosted_services.html": {
+			HostedServices{HostedServices: []HostedService{{Namespace: "ns-one", Cluster: "live", Application: "Tracker", TeamName: "webops"}}, TotalNamespaces: 1, Clusters: []string{"live"}, Listing: listing},
+			[]string{"ns-one", "Tracker"},
+		},
+		"infrastructure_deployments.html": {
+			InfrastructureDeployments{
+				InfrastructureDeployments: hoodaw.InfrastructureDeployments{
+					Deployments: []MonthlyDeployments{{Date: "2024-01", Deployed: "10", Failed: "1"}},
+					Weeks:       []DeploymentWeek{{Start: "2024-01-01", Deployments: 2}},
+					Failures:    []DeploymentFailure{{Number: 7, Title: "Revert <everything>", URL: "https://github.com/pr/7"}},
+				},
+				WeeklyChart: `[["2024-01-01",2,0,0]]`,
+			},
+			[]string{`data.addRows([["2024-01-01",2,0,0]])`, "Revert &lt;everything&gt;", `href="https://github.com/pr/7"`},
+		},
+		"live_one_domains.html": {
+			Domains{Data: []Domain{domain}, Total: 1, Clusters: []string{"live"}, Migrations: []DomainMigration{{Pattern: "live-1.cloud-platform.service.justice.gov.uk", Total: 1, Teams: []TeamDomains{{Team: "webops", Domains: []Domain{domain}}}}}, Dangling: []ReachableDomain{{ReachableDomain: hoodaw.ReachableDomain{Cluster: "live", Namespace: "ns-one", Hostname: "old.service.justice.gov.uk", Target: "old.s3.amazonaws.com", Status: "dangling"}}}, Listing: listing},
+			[]string{"tracker.apps.live-1.cloud-platform.service.justice.gov.uk", "webops", "Dangling domains", "old.s3.amazonaws.com"},
+		},
+		"namespace_compliance.html": {
+			NamespaceCompliance{Namespaces: []NonCompliantNamespace{{NonCompliantNamespace: hoodaw.NonCompliantNamespace{Name: "ns-one", Cluster: "live", Issues: []string{"no team name"}}}}, NamespacesChecked: 2, Total: 1, Listing: listing},
+			[]string{"ns-one", "no team name"},
+		},
+		"namespace_costs.html": {
+			Costs{Rows: []NamespaceCostRow{{Namespace: "ns-one", NamespaceCost: NamespaceCost{Total: 12.5, Clusters: []string{"live"}}}}, Total: 12.5, Clusters: []string{"live"}, Listing: listing},
+			[]string{"ns-one", "12.5"},
+		},
+		"namespace_not_found.html": {
+			NamespaceNotFound{Namespace: "ns one", Similar: []string{"ns-one"}},
+			[]string{`href="/namespace/ns-one"`, `href="/search?q=ns%20one"`},
+		},
+		"namespaces.html": {
+			usage,
+			[]string{"ns-one", `href="?cluster=live-2"`, `href="/team/webops"`, "tracker-api", "timeout &lt;b&gt;applying&lt;/b&gt;"},
+		},
+		"search.html": {
+			Search{Query: `"webops"`, Hits: []SearchHit{{Type: HitNamespace, Title: "ns-one", Team: "webops", URL: "/namespace/ns-one?cluster=live"}}, Total: 1, Types: []string{HitNamespace}, Listing: listing},
+			[]string{`value="&#34;webops&#34;"`, `href="/namespace/ns-one?cluster=live"`},
+		},
+		"team.html": {
+			Team{Name: "webops", Namespaces: []TeamNamespace{{Namespace: "ns-one", Cluster: "live", Cost: 12.5}}, TotalCost: 12.5, HelmReleases: []TeamHelmRelease{{Cluster: "live", HelmRelease: release}}, Domains: []Domain{domain}, ErroringNamespaces: []NamespaceError{namespaceError}},
+			[]string{"webops", `href="/namespace/ns-one?cluster=live"`, "$12.50"},
+		},
+	}
+}
+
+func Test_render(t *testing.T) {
+	fixtures := renderFixtures(t)
+
+	for name := range pages {
+		if _, ok := fixtures[name]; !ok {
+			t.Errorf("page %s has no fixture to render", name)
+		}
+	}
+
+	for name, f := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if err := render(w, http.StatusOK, name, f.data); err != nil {
+				t.Fatal(err)
+			}
+
+			body := w.Body.String()
+			if !strings.Contains(body, `<nav class="navbar`) || !strings.HasSuffix(strings.TrimSpace(body), "</html>") {
+				t.Error("render() didn't render the page in the layout")
+			}
+			if strings.Contains(body, "ZgotmplZ") {
+				t.Error("render() escaped an unsafe value")
+			}
+			for _, want := range f.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("render() doesn't contain %s", want)
+				}
+			}
+		})
+	}
+}
+
+func Test_render_unknownPage(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := render(w, http.StatusOK, "nothing.html", nil); err == nil {
+		t.Error("render() of an unknown page didn't return an error")
+	}
+	if w.Body.Len() > 0 {
+		t.Error("render() of an unknown page wrote a body")
+	}
+}
@@ -0,0 +1,38 @@
+package lib
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ReportDataPage writes a report as it is stored in the hoodaw bucket, for the API clients of the
+// reports which have no page of their own. It is JSON unless YAML is asked for, as a report has
+// no CSV of its own, and a 404 for a report which isn't known.
+func ReportDataPage(w http.ResponseWriter, bucket, name string, format Format, client *s3.Client) error {
+	return reportDataPage(w, s3Load(client), bucket, name, format)
+}
+
+func reportDataPage(w http.ResponseWriter, load loadFunc, bucket, name string, format Format) error {
+	key := name + ".json"
+	schema, ok := reportSchemas[key]
+	if !ok {
+		return &StatusError{Status: http.StatusNotFound, Err: fmt.Errorf("no report called %q", name)}
+	}
+
+	switch format {
+	case FormatCSV:
+		return &StatusError{Status: http.StatusNotAcceptable, Err: fmt.Errorf("the %s report is only available as json or yaml", name)}
+	case FormatHTML:
+		format = FormatJSON
+	}
+
+	// decoding the report checks it is valid json of the type the pages use
+	data, _, err := readReport(load, bucket, key, schema.target())
+	if err != nil {
+		return err
+	}
+
+	return writeReport(w, format, name, data, nil)
+}
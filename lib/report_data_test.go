@@ -0,0 +1,48 @@
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_reportDataPage(t *testing.T) {
+	reports := fakeReports{
+		"hoodaw/domain_reachability.json": {`{"updated_at": "2024-01-2 10:4:5 UTC", "domains": [{"Hostname": "old.service.justice.gov.uk", "Status": "dangling"}], "dangling": 1}`, "1"},
+		"hoodaw/namespace_usage.json":     {`{"updated_at": "2024-01-2 10:4:5 UTC", "data": "not a list"}`, "1"},
+	}
+
+	tests := []struct {
+		name            string
+		report          string
+		format          Format
+		wantStatus      int
+		wantContentType string
+	}{
+		{"json", "domain_reachability", FormatJSON, 0, "application/json"},
+		{"html is json", "domain_reachability", FormatHTML, 0, "application/json"},
+		{"yaml", "domain_reachability", FormatYAML, 0, "application/yaml"},
+		{"csv", "domain_reachability", FormatCSV, http.StatusNotAcceptable, ""},
+		{"unknown report", "passwords", FormatJSON, http.StatusNotFound, ""},
+		{"not the type of the report", "namespace_usage", FormatJSON, http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			err := reportDataPage(w, reports.load, "hoodaw", tt.report, tt.format)
+			if tt.wantStatus != 0 {
+				if err == nil || errorStatus(err) != tt.wantStatus {
+					t.Fatalf("reportDataPage() error = %v, want a %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+require "yaml"
+require "time"
+require "json"
+require "open-uri"
+
+# Finds the reports whose cronjob has stopped writing them: those updated longer ago than their
+# maximum age in config/report_freshness.yaml, or never written at all
+class ReportFreshness
+  CONFIG_FILE = "config/report_freshness.yaml"
+
+  # The go web application's /freshness is read while rendering the dashboard, so it is read at
+  # most once a minute and given up on quickly
+  GO_CACHE_SECONDS = 60
+  GO_OPEN_TIMEOUT = 2
+  GO_READ_TIMEOUT = 3
+
+  @go_cache = {}
+  @go_cache_lock = Mutex.new
+
+  class << self
+    attr_reader :go_cache, :go_cache_lock
+  end
+
+  attr_reader :logger
+
+  def initialize(params)
+    @logger = params.fetch(:logger)
+    @config = params.fetch(:config) { YAML.load_file(CONFIG_FILE) }
+    @now = params.fetch(:now) { Time.now }
+    @go_freshness_url = params.fetch(:go_freshness_url, ENV["GO_FRESHNESS_URL"])
+  end
+
+  # reports is when each report was updated, by the path it is posted to
+  def stale_reports(reports)
+    reports.select { |name, updated_at| stale?(name, updated_at) }.keys.sort
+  end
+
+  def stale?(name, updated_at)
+    return true if updated_at.to_s.empty?
+
+    age_hours = (@now - Time.parse(updated_at.to_s)) / 3600
+    age_hours > max_age_hours(name)
+  end
+
+  def max_age_hours(name)
+    @config.fetch("ruby", {}).fetch(name.to_s, @config.fetch("default_max_age_hours"))
+  end
+
+  # The number of stale reports of the go web application, from its /freshness page, as read in
+  # the last minute. It is nil when the page can't be read, so the dashboard is shown without it.
+  def go_stale_count
+    return 0 if @go_freshness_url.to_s.empty?
+
+    self.class.go_cache_lock.synchronize do
+      cached = self.class.go_cache[@go_freshness_url]
+      return cached[:count] if cached && @now - cached[:read_at] < GO_CACHE_SECONDS
+
+      count = read_go_stale_count
+      self.class.go_cache[@go_freshness_url] = {count: count, read_at: @now}
+      count
+    end
+  end
+
+  private
+
+  def read_go_stale_count
+    json = URI.open(@go_freshness_url, "Accept" => "application/json", open_timeout: GO_OPEN_TIMEOUT, read_timeout: GO_READ_TIMEOUT) { |f| f.read }
+    JSON.parse(json).fetch("stale")
+  rescue => e
+    logger.info "Unable to read the go report freshness from #{@go_freshness_url}: #{e.message}"
+    nil
+  end
+end
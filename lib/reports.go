@@ -0,0 +1,142 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+)
+
+// reportObject is where the json of a report is kept
+type reportObject struct {
+	bucket, key string
+}
+
+// loadFunc reads the json of a report and when it was written
+type loadFunc func(bucket, key string) ([]byte, string, error)
+
+// s3Load reads reports from S3
+func s3Load(client *s3.Client) loadFunc {
+	return func(bucket, key string) ([]byte, string, error) {
+		data, filestamp, err := utils.ImportS3File(client, bucket, key)
+		countFetchError(key, err)
+		return data, filestamp, err
+	}
+}
+
+// statFunc returns when a report was last written
+type statFunc func(ctx context.Context, bucket, key string) (time.Time, error)
+
+// s3Stat returns when reports in S3 were last written, without reading them
+func s3Stat(client *s3.Client) statFunc {
+	return func(ctx context.Context, bucket, key string) (time.Time, error) {
+		lastModified, err := utils.S3FileLastModified(ctx, client, bucket, key)
+		countFetchError(key, err)
+		return lastModified, err
+	}
+}
+
+// versionsFunc lists up to limit of the latest versions of a report, newest first
+type versionsFunc func(bucket, key string, limit int) ([]utils.S3FileVersion, error)
+
+// loadVersionFunc reads the json of a version of a report
+type loadVersionFunc func(bucket, key, versionID string) ([]byte, error)
+
+// s3Versions lists the versions of reports in versioned S3 buckets
+func s3Versions(client *s3.Client) versionsFunc {
+	return func(bucket, key string, limit int) ([]utils.S3FileVersion, error) {
+		versions, err := utils.ListS3FileVersions(client, bucket, key, limit)
+		countFetchError(key, err)
+		return versions, err
+	}
+}
+
+// s3LoadVersion reads versions of reports from versioned S3 buckets
+func s3LoadVersion(client *s3.Client) loadVersionFunc {
+	return func(bucket, key, versionID string) ([]byte, error) {
+		data, err := utils.ImportS3FileVersion(client, bucket, key, versionID)
+		countFetchError(key, err)
+		return data, err
+	}
+}
+
+// namespaceReports are the reports with results for namespaces, by name, which are joined
+// together by the namespace and team pages and the search index
+func namespaceReports(bucket, errorNsBucket string) map[string]reportObject {
+	return map[string]reportObject{
+		"hosted_services":     {bucket, "hosted_services.json"},
+		"live_one_domains":    {bucket, "live_one_domains.json"},
+		"helm_releases":       {bucket, "helm_releases.json"},
+		"namespace_costs":     {bucket, "namespace_costs.json"},
+		"namespace_usage":     {bucket, "namespace_usage.json"},
+		"erroring_namespaces": {errorNsBucket, erroringNamespacesKey},
+	}
+}
+
+// pageReports are all the reports the pages are rendered from, by name
+func pageReports(bucket, errorNsBucket string) map[string]reportObject {
+	reports := namespaceReports(bucket, errorNsBucket)
+	reports["namespace_compliance"] = reportObject{bucket, "namespace_compliance.json"}
+	reports["certificate_expiry"] = reportObject{bucket, "certificate_expiry.json"}
+	reports["infrastructure_deployments"] = reportObject{bucket, "infrastructure_deployments.json"}
+
+	return reports
+}
+
+// loadReports reads the json of the reports and when each was written, by name, along with the
+// error of each report which couldn't be read
+func loadReports(load loadFunc, reports map[string]reportObject) (map[string][]byte, map[string]string, map[string]error) {
+	data := make(map[string][]byte)
+	stamps := make(map[string]string)
+	errs := make(map[string]error)
+
+	for name, r := range reports {
+		byteValue, filestamp, err := load(r.bucket, r.key)
+		if err != nil {
+			errs[name] = &ReportError{Key: r.key, Err: err}
+			continue
+		}
+		data[name], stamps[name] = byteValue, filestamp
+	}
+
+	return data, stamps, errs
+}
+
+// joinReportErrors joins the errors of the reports, in the order of their names
+func joinReportErrors(errs map[string]error) error {
+	joined := make([]error, 0, len(errs))
+	for _, name := range sortedKeys(errs) {
+		joined = append(joined, errs[name])
+	}
+
+	return errors.Join(joined...)
+}
+
+// logReportErrors logs the reports a page is shown without
+func logReportErrors(errs map[string]error) {
+	for _, name := range sortedKeys(errs) {
+		var re *ReportError
+		if errors.As(errs[name], &re) {
+			slog.Warn("showing page without report", "report", re.Key, "error", re.Err)
+		}
+	}
+}
+
+// decodeReport decodes the json of the report with the name, as read by loadReports, into v. A
+// report which wasn't read leaves v empty, one which isn't valid json is a ReportError with its key.
+func decodeReport(data map[string][]byte, name string, v any) error {
+	byteValue, ok := data[name]
+	if !ok {
+		return nil
+	}
+
+	if err := json.Unmarshal(byteValue, v); err != nil {
+		return &ReportError{Key: namespaceReports("", "")[name].key, Err: err}
+	}
+
+	return nil
+}
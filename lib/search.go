@@ -0,0 +1,395 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// The types of search hit, by the report they come from
+const (
+	HitNamespace         = "namespace"
+	HitHostname          = "hostname"
+	HitDomain            = "domain"
+	HitHelmRelease       = "helm_release"
+	HitCost              = "cost"
+	HitErroringNamespace = "erroring_namespace"
+	HitUsage             = "usage"
+)
+
+// SearchHit is a result of a report matching a search, linking to the page which shows it
+type SearchHit struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Detail    string `json:"detail"`
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Team      string `json:"team"`
+	URL       string `json:"url"`
+}
+
+// searchEntry is a hit with the lower cased text it is found by
+type searchEntry struct {
+	hit  SearchHit
+	text string
+}
+
+// SearchIndex is an in memory index of the reports, rebuilt when any of them is updated
+type SearchIndex struct {
+	load    loadFunc
+	reports map[string]reportObject
+
+	onRefresh []func(data map[string][]byte)
+
+	mu          sync.RWMutex
+	entries     []searchEntry
+	stamps      map[string]string
+	lastUpdated string
+}
+
+// NewSearchIndex returns an index of the reports in the hoodaw bucket and the erroring namespaces
+// of the apply-live bucket. It is empty until it is refreshed.
+func NewSearchIndex(client *s3.Client, bucket, errorNsBucket string) *SearchIndex {
+	return &SearchIndex{
+		load:    s3Load(client),
+		reports: namespaceReports(bucket, errorNsBucket),
+	}
+}
+
+// Refresh reads the reports and rebuilds the index when any of them has been updated since the
+// last refresh. Reports which can't be read are left out of the index until they can, and the
+// index is kept as it was when one isn't valid json.
+func (ix *SearchIndex) Refresh() error {
+	data, stamps, errs := loadReports(ix.load, ix.reports)
+
+	ix.mu.RLock()
+	changed := fmt.Sprint(stamps) != fmt.Sprint(ix.stamps)
+	ix.mu.RUnlock()
+
+	if changed {
+		entries, err := buildSearchIndex(data)
+		if err != nil {
+			return fmt.Errorf("unable to index: %w", err)
+		}
+
+		ix.mu.Lock()
+		ix.entries, ix.stamps, ix.lastUpdated = entries, stamps, time.Now().UTC().Format("2006-01-02 15:04:05 UTC")
+		ix.mu.Unlock()
+
+		for _, f := range ix.onRefresh {
+			f(data)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to index: %w", joinReportErrors(errs))
+	}
+
+	return nil
+}
+
+// OnRefresh calls f with the json of the reports, by name, each time the index is rebuilt from
+// them. It is set before the index is first refreshed.
+func (ix *SearchIndex) OnRefresh(f func(data map[string][]byte)) {
+	ix.onRefresh = append(ix.onRefresh, f)
+}
+
+// RefreshEvery refreshes the index straight away then every interval, logging the reports which
+// couldn't be read
+func (ix *SearchIndex) RefreshEvery(interval time.Duration) {
+	for {
+		if err := ix.Refresh(); err != nil {
+			slog.Error("unable to refresh the search index", "error", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// Search returns the hits with every word of the query, those titled by the query first then
+// those whose title starts with it
+func (ix *SearchIndex) Search(q string) []SearchHit {
+	terms := strings.Fields(strings.ToLower(q))
+	if len(terms) == 0 {
+		return []SearchHit{}
+	}
+	phrase := strings.Join(terms, " ")
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	type scored struct {
+		hit   SearchHit
+		score int
+	}
+	matches := make([]scored, 0)
+	for _, e := range ix.entries {
+		match := true
+		for _, term := range terms {
+			if !strings.Contains(e.text, term) {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+
+		title := strings.ToLower(e.hit.Title)
+		score := 0
+		switch {
+		case title == phrase:
+			score = 2
+		case strings.HasPrefix(title, phrase):
+			score = 1
+		}
+		matches = append(matches, scored{e.hit, score})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.hit.Type != b.hit.Type {
+			return a.hit.Type < b.hit.Type
+		}
+		return a.hit.Title < b.hit.Title
+	})
+
+	hits := make([]SearchHit, len(matches))
+	for i, m := range matches {
+		hits[i] = m.hit
+	}
+
+	return hits
+}
+
+// LastUpdated is when the index was last rebuilt
+func (ix *SearchIndex) LastUpdated() string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return ix.lastUpdated
+}
+
+// buildSearchIndex returns the entries of the json of each report, by the name of the report.
+// The namespaces of the other reports are searchable by the team which owns them. It fails when a
+// report isn't valid json.
+func buildSearchIndex(data map[string][]byte) ([]searchEntry, error) {
+	entries := make([]searchEntry, 0)
+	add := func(hit SearchHit, text ...string) {
+		text = append(text, hit.Title, hit.Detail, hit.Cluster, hit.Namespace, hit.Team)
+		entries = append(entries, searchEntry{hit: hit, text: strings.ToLower(strings.Join(text, " "))})
+	}
+
+	var hostedServices HostedServices
+	if err := decodeReport(data, "hosted_services", &hostedServices); err != nil {
+		return nil, err
+	}
+	owners := newNamespaceOwners(hostedServices.HostedServices)
+
+	for _, hs := range hostedServices.HostedServices {
+		add(SearchHit{
+			Type:      HitNamespace,
+			Title:     hs.Namespace,
+			Detail:    strings.Join(nonEmpty(hs.Application, hs.BusinessUnit), ", "),
+			Cluster:   hs.Cluster,
+			Namespace: hs.Namespace,
+			Team:      hs.TeamName,
+			URL:       namespaceURL(hs.Namespace, hs.Cluster),
+		}, hs.SlackChannel, hs.SourceCode, hs.DeploymentType, strings.Join(hs.DomainNames, " "))
+
+		for _, ing := range hs.Ingresses {
+			add(SearchHit{
+				Type:      HitHostname,
+				Title:     ing.Hostname,
+				Detail:    fmt.Sprintf("ingress %s in %s", ing.Ingress, hs.Namespace),
+				Cluster:   hs.Cluster,
+				Namespace: hs.Namespace,
+				Team:      hs.TeamName,
+				URL:       namespaceURL(hs.Namespace, hs.Cluster),
+			}, ing.Ingress, ing.IngressClass)
+		}
+	}
+
+	var domains Domains
+	if err := decodeReport(data, "live_one_domains", &domains); err != nil {
+		return nil, err
+	}
+	for _, d := range domains.Data {
+		add(SearchHit{
+			Type:      HitDomain,
+			Title:     d.URL,
+			Detail:    fmt.Sprintf("ingress %s to migrate off %s", d.IngressName, d.Pattern),
+			Cluster:   d.Cluster,
+			Namespace: d.Namespace,
+			Team:      owners.lookup(d.Cluster, d.Namespace).TeamName,
+			URL:       "/live_one_domains?" + url.Values{"hostname": {d.URL}}.Encode(),
+		}, d.IngressName)
+	}
+
+	var helmReleases HelmReleases
+	if err := decodeReport(data, "helm_releases", &helmReleases); err != nil {
+		return nil, err
+	}
+	for _, c := range helmReleases.Clusters {
+		for _, h := range c.HelmReleases {
+			add(SearchHit{
+				Type:      HitHelmRelease,
+				Title:     h.Name,
+				Detail:    fmt.Sprintf("%s %s, latest %s", h.Chart, h.InstalledVersion, h.LatestVersion),
+				Cluster:   c.ClusterName,
+				Namespace: h.Namespace,
+				Team:      owners.lookup(c.ClusterName, h.Namespace).TeamName,
+				URL:       "/helm_whatup?" + url.Values{"cluster": {c.ClusterName}, "name": {h.Name}}.Encode(),
+			})
+		}
+	}
+
+	var costs Costs
+	if err := decodeReport(data, "namespace_costs", &costs); err != nil {
+		return nil, err
+	}
+	for _, ns := range sortedKeys(costs.Namespaces) {
+		c := costs.Namespaces[ns]
+		add(SearchHit{
+			Type:      HitCost,
+			Title:     ns,
+			Detail:    fmt.Sprintf("$%.2f a month", c.Total),
+			Cluster:   joinCSV(c.Clusters),
+			Namespace: ns,
+			Team:      owners.lookup("", ns).TeamName,
+			URL:       "/costs_by_namespace?" + url.Values{"namespace": {ns}}.Encode(),
+		})
+	}
+
+	// apply-live only applies the namespaces of the live cluster
+	var erroringNamespaces []NamespaceError
+	if err := decodeReport(data, "erroring_namespaces", &erroringNamespaces); err != nil {
+		return nil, err
+	}
+	for _, e := range erroringNamespaces {
+		add(SearchHit{
+			Type:      HitErroringNamespace,
+			Title:     e.Namespace,
+			Detail:    fmt.Sprintf("failing to apply in build %d", e.BuildID),
+			Cluster:   "live",
+			Namespace: e.Namespace,
+			Team:      owners.lookup("live", e.Namespace).TeamName,
+			URL:       "/erroring_namespaces?" + url.Values{"namespace": {e.Namespace}}.Encode(),
+		}, e.Error)
+	}
+
+	var usage NamespaceUsage
+	if err := decodeReport(data, "namespace_usage", &usage); err != nil {
+		return nil, err
+	}
+	for _, u := range usage.Namespaces {
+		add(SearchHit{
+			Type:      HitUsage,
+			Title:     u.Name,
+			Detail:    fmt.Sprintf("%d pods, %d containers", u.Used.Pods, u.ContainerCount),
+			Cluster:   u.Cluster,
+			Namespace: u.Name,
+			Team:      owners.lookup(u.Cluster, u.Name).TeamName,
+			URL:       namespaceURL(u.Name, u.Cluster),
+		})
+	}
+
+	return entries, nil
+}
+
+func namespaceURL(namespace, cluster string) string {
+	u := "/namespace/" + url.PathEscape(namespace)
+	if cluster != "" {
+		u += "?" + url.Values{"cluster": {cluster}}.Encode()
+	}
+
+	return u
+}
+
+func nonEmpty(values ...string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
+
+// Search is the search page
+type Search struct {
+	Query       string
+	Hits        []SearchHit
+	Total       int
+	LastUpdated string
+	Type        string
+	Types       []string
+	Listing     Listing
+}
+
+// searchHitColumns are the columns the hits can be filtered and sorted by
+var searchHitColumns = []column[SearchHit]{
+	{name: "type", value: func(h SearchHit) string { return h.Type }},
+	{name: "title", value: func(h SearchHit) string { return h.Title }},
+	{name: "cluster", value: func(h SearchHit) string { return h.Cluster }},
+	{name: "namespace", value: func(h SearchHit) string { return h.Namespace }},
+	{name: "team", value: func(h SearchHit) string { return h.Team }},
+}
+
+// SearchPage shows the hits of the search q, filtered, sorted and paged by the query
+func SearchPage(w http.ResponseWriter, index *SearchIndex, q string, query Query, format Format) error {
+	hits := index.Search(q)
+
+	types := make([]string, 0, len(hits))
+	for _, h := range hits {
+		types = append(types, h.Type)
+	}
+
+	matching, err := queryRows(query, hits, searchHitColumns)
+	if err != nil {
+		return err
+	}
+	page, listing := paginate(query, matching)
+
+	if format != FormatHTML {
+		return writeRows(w, format, "search", nil, "", page, listing, searchHitsCSV)
+	}
+
+	search := Search{
+		Query:       q,
+		Hits:        page,
+		Total:       len(matching),
+		LastUpdated: index.LastUpdated(),
+		Type:        query.Filters["type"],
+		Types:       filterOptions(types),
+		Listing:     listing,
+	}
+
+	return render(w, http.StatusOK, "search.html", search)
+}
+
+// searchHitsCSV is a row for each hit
+func searchHitsCSV(data []byte) (csvTable, error) {
+	var hits []SearchHit
+	if err := json.Unmarshal(data, &hits); err != nil {
+		return csvTable{}, err
+	}
+
+	table := csvTable{header: []string{"type", "title", "detail", "cluster", "namespace", "team", "url"}}
+	for _, h := range hits {
+		table.rows = append(table.rows, []string{h.Type, h.Title, h.Detail, h.Cluster, h.Namespace, h.Team, h.URL})
+	}
+
+	return table, nil
+}
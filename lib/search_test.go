@@ -0,0 +1,124 @@
+package lib
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeReports are the reports of a fake bucket, with the time each was written
+type fakeReports map[string]struct {
+	data  string
+	stamp string
+}
+
+func (f fakeReports) load(bucket, key string) ([]byte, string, error) {
+	r, ok := f[bucket+"/"+key]
+	if !ok {
+		return nil, "", errors.New("NoSuchKey")
+	}
+	return []byte(r.data), r.stamp, nil
+}
+
+func testSearchIndex(reports fakeReports) *SearchIndex {
+	ix := NewSearchIndex(nil, "hoodaw", "apply-live")
+	ix.load = reports.load
+	return ix
+}
+
+func TestSearchIndex_Search(t *testing.T) {
+	reports := fakeReports{
+		"hoodaw/hosted_services.json": {`{"namespace_details": [
+			{"Name": "ns-one", "Cluster": "live", "Application": "Tracker", "TeamName": "webops", "Ingresses": [{"Hostname": "tracker.service.justice.gov.uk", "Ingress": "tracker"}]},
+			{"Name": "ns-two", "Cluster": "live", "Application": "Payments", "TeamName": "finance-dev"}
+		]}`, "1"},
+		"hoodaw/live_one_domains.json":        {`{"live_one_domains": [{"cluster": "live", "namespace": "ns-one", "ingress": "old", "hostname": "tracker.apps.live-1.cloud-platform.service.justice.gov.uk", "pattern": "live-1.cloud-platform.service.justice.gov.uk"}]}`, "1"},
+		"hoodaw/helm_releases.json":           {`{"clusters": [{"name": "live", "apps": [{"name": "tracker-api", "namespace": "ns-one", "chart": "api-1.0.0"}]}]}`, "1"},
+		"hoodaw/namespace_costs.json":         {`{"namespace": {"ns-two": {"total": 12.5, "clusters": ["live"]}}}`, "1"},
+		"hoodaw/namespace_usage.json":         {`{"data": [{"Name": "ns-one", "Cluster": "live", "ContainerCount": 3}]}`, "1"},
+		"apply-live/" + erroringNamespacesKey: {`[{"namespace": "ns-one", "error": "timeout applying", "build_id": 42}]`, "1"},
+	}
+
+	ix := testSearchIndex(reports)
+	if err := ix.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		q    string
+		want []string
+	}{
+		{"nothing", " ", []string{}},
+		{
+			"everything about a team",
+			"webops",
+			[]string{"domain tracker.apps.live-1.cloud-platform.service.justice.gov.uk", "erroring_namespace ns-one", "helm_release tracker-api", "hostname tracker.service.justice.gov.uk", "namespace ns-one", "usage ns-one"},
+		},
+		{"where a hostname comes from", "TRACKER.service", []string{"hostname tracker.service.justice.gov.uk"}},
+		{"every word", "payments finance", []string{"namespace ns-two"}},
+		{"titles first", "ns-two", []string{"cost ns-two", "namespace ns-two"}},
+		{"error messages", "timeout", []string{"erroring_namespace ns-one"}},
+		{"no hits", "nothing-here", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, h := range ix.Search(tt.q) {
+				got = append(got, h.Type+" "+h.Title)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.q, got, tt.want)
+			}
+		})
+	}
+
+	hits := ix.Search("tracker-api")
+	if want := "/helm_whatup?cluster=live&name=tracker-api"; len(hits) != 1 || hits[0].URL != want || hits[0].Team != "webops" {
+		t.Errorf("Search() = %+v, want a hit of webops linking to %s", hits, want)
+	}
+}
+
+func TestSearchIndex_Refresh(t *testing.T) {
+	reports := fakeReports{
+		"hoodaw/hosted_services.json": {`{"namespace_details": [{"Name": "ns-one", "Cluster": "live"}]}`, "1"},
+	}
+
+	ix := testSearchIndex(reports)
+	if err := ix.Refresh(); err == nil {
+		t.Error("Refresh() of missing reports didn't return an error")
+	}
+	if got := len(ix.Search("ns-one")); got != 1 {
+		t.Fatalf("Search() = %d hits, want 1", got)
+	}
+	updated := ix.LastUpdated()
+
+	// the index is only rebuilt when a report is written again
+	ix.entries = nil
+	ix.Refresh()
+	if ix.LastUpdated() != updated || len(ix.Search("ns-one")) != 0 {
+		t.Error("Refresh() rebuilt the index when no report had changed")
+	}
+
+	reports["hoodaw/hosted_services.json"] = struct {
+		data  string
+		stamp string
+	}{`{"namespace_details": [{"Name": "ns-two", "Cluster": "live"}]}`, "2"}
+	ix.Refresh()
+	if len(ix.Search("ns-one")) != 0 || len(ix.Search("ns-two")) != 1 {
+		t.Error("Refresh() didn't rebuild the index from the updated report")
+	}
+
+	// a report which isn't valid json keeps the index as it was
+	reports["hoodaw/hosted_services.json"] = struct {
+		data  string
+		stamp string
+	}{`{"namespace_details": [`, "3"}
+	var reportErr *ReportError
+	if err := ix.Refresh(); !errors.As(err, &reportErr) || reportErr.Key != "hosted_services.json" {
+		t.Errorf("Refresh() of an invalid report = %v, want an error naming hosted_services.json", err)
+	}
+	if len(ix.Search("ns-two")) != 1 {
+		t.Error("Refresh() of an invalid report dropped the index")
+	}
+}
@@ -0,0 +1,256 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+)
+
+// Team is what the reports say about the namespaces with a team-name annotation
+type Team struct {
+	Name               string            `json:"team"`
+	SlackChannels      []string          `json:"slack_channels"`
+	Namespaces         []TeamNamespace   `json:"namespaces"`
+	TotalCost          float64           `json:"total_cost"`
+	Usage              TeamUsage         `json:"usage"`
+	HelmReleases       []TeamHelmRelease `json:"out_of_date_helm_releases"`
+	Domains            []Domain          `json:"live_one_domains"`
+	ErroringNamespaces []NamespaceError  `json:"erroring_namespaces"`
+	LastUpdated        string            `json:"-"`
+}
+
+// TeamNamespace is a namespace of a team with its monthly cost and resource usage
+type TeamNamespace struct {
+	Namespace    string  `json:"namespace"`
+	Cluster      string  `json:"cluster"`
+	Application  string  `json:"application"`
+	BusinessUnit string  `json:"business_unit"`
+	SlackChannel string  `json:"slack_channel"`
+	SourceCode   string  `json:"source_code"`
+	Cost         float64 `json:"cost"`
+	TeamUsage
+}
+
+// TeamUsage is the resources used by namespaces, CPU in millicores and memory in mebibytes
+type TeamUsage struct {
+	CPURequested    int `json:"cpu_requested"`
+	CPUUsed         int `json:"cpu_used"`
+	MemoryRequested int `json:"memory_requested"`
+	MemoryUsed      int `json:"memory_used"`
+	Pods            int `json:"pods"`
+	Containers      int `json:"containers"`
+}
+
+func (u *TeamUsage) add(o TeamUsage) {
+	u.CPURequested += o.CPURequested
+	u.CPUUsed += o.CPUUsed
+	u.MemoryRequested += o.MemoryRequested
+	u.MemoryUsed += o.MemoryUsed
+	u.Pods += o.Pods
+	u.Containers += o.Containers
+}
+
+// TeamHelmRelease is a helm release of a team behind the latest version of its chart
+type TeamHelmRelease struct {
+	Cluster string `json:"cluster"`
+	HelmRelease
+}
+
+// TeamPage shows the namespaces of a team with their costs, usage, out of date helm releases,
+// domains to migrate and namespaces failing to apply. A team is the team-name annotation of its
+// namespaces, matched whatever its case.
+func TeamPage(w http.ResponseWriter, bucket, errorNsBucket, name string, format Format, client *s3.Client) error {
+	// the team's namespaces come from the hosted services, the other reports are left out when
+	// they can't be read
+	data, stamps, errs := loadReports(s3Load(client), namespaceReports(bucket, errorNsBucket))
+	if err, ok := errs["hosted_services"]; ok {
+		return err
+	}
+	logReportErrors(errs)
+
+	team, ok, err := buildTeam(name, data)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &StatusError{Status: http.StatusNotFound, Err: fmt.Errorf("no namespaces have the team name %q", name)}
+	}
+	team.LastUpdated = stamps["hosted_services"]
+
+	if format != FormatHTML {
+		out, err := json.Marshal(team)
+		if err != nil {
+			return err
+		}
+		return writeReport(w, format, "team", out, teamCSV)
+	}
+
+	return render(w, http.StatusOK, "team.html", team)
+}
+
+// buildTeam joins the results of the reports, by name, for the namespaces of the team, and
+// reports whether it has any. It fails when a report isn't valid json.
+func buildTeam(name string, data map[string][]byte) (Team, bool, error) {
+	var hostedServices HostedServices
+	if err := decodeReport(data, "hosted_services", &hostedServices); err != nil {
+		return Team{}, false, err
+	}
+	owners := newNamespaceOwners(hostedServices.HostedServices)
+
+	owned := func(cluster, namespace string) bool {
+		return strings.EqualFold(owners.lookup(cluster, namespace).TeamName, name)
+	}
+
+	team := Team{
+		Name:               name,
+		SlackChannels:      make([]string, 0),
+		Namespaces:         make([]TeamNamespace, 0),
+		HelmReleases:       make([]TeamHelmRelease, 0),
+		Domains:            make([]Domain, 0),
+		ErroringNamespaces: make([]NamespaceError, 0),
+	}
+
+	slackChannels := make([]string, 0)
+	for _, hs := range hostedServices.HostedServices {
+		if !strings.EqualFold(hs.TeamName, name) {
+			continue
+		}
+		if len(team.Namespaces) == 0 {
+			team.Name = hs.TeamName
+		}
+		slackChannels = append(slackChannels, hs.SlackChannel)
+		team.Namespaces = append(team.Namespaces, TeamNamespace{
+			Namespace:    hs.Namespace,
+			Cluster:      hs.Cluster,
+			Application:  hs.Application,
+			BusinessUnit: hs.BusinessUnit,
+			SlackChannel: hs.SlackChannel,
+			SourceCode:   hs.SourceCode,
+		})
+	}
+	if len(team.Namespaces) == 0 {
+		return Team{}, false, nil
+	}
+	team.SlackChannels = filterOptions(slackChannels)
+
+	sort.SliceStable(team.Namespaces, func(i, j int) bool {
+		a, b := team.Namespaces[i], team.Namespaces[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Cluster < b.Cluster
+	})
+
+	// costs aren't split by cluster, so a namespace in more than one cluster is charged once
+	var costs Costs
+	if err := decodeReport(data, "namespace_costs", &costs); err != nil {
+		return Team{}, false, err
+	}
+	charged := make(map[string]bool)
+	for i, ns := range team.Namespaces {
+		cost, ok := costs.Namespaces[ns.Namespace]
+		if !ok || charged[ns.Namespace] {
+			continue
+		}
+		charged[ns.Namespace] = true
+		team.Namespaces[i].Cost = float64(cost.Total)
+		team.TotalCost += float64(cost.Total)
+	}
+
+	var usage NamespaceUsage
+	if err := decodeReport(data, "namespace_usage", &usage); err != nil {
+		return Team{}, false, err
+	}
+	for _, u := range usage.Namespaces {
+		for i, ns := range team.Namespaces {
+			if ns.Namespace != u.Name || !matchesFilter(u.Cluster, ns.Cluster) {
+				continue
+			}
+			used := TeamUsage{
+				CPURequested:    u.Requested.CPU,
+				CPUUsed:         u.Used.CPU,
+				MemoryRequested: u.Requested.Memory,
+				MemoryUsed:      u.Used.Memory,
+				Pods:            u.Used.Pods,
+				Containers:      u.ContainerCount,
+			}
+			team.Namespaces[i].TeamUsage = used
+			team.Usage.add(used)
+			break
+		}
+	}
+
+	var helmReleases HelmReleases
+	if err := decodeReport(data, "helm_releases", &helmReleases); err != nil {
+		return Team{}, false, err
+	}
+	for _, c := range helmReleases.Clusters {
+		for _, h := range c.HelmReleases {
+			h.State = utils.CompareVersions(h.InstalledVersion, h.LatestVersion)
+			if h.State != "success" && owned(c.ClusterName, h.Namespace) {
+				team.HelmReleases = append(team.HelmReleases, TeamHelmRelease{Cluster: c.ClusterName, HelmRelease: h})
+			}
+		}
+	}
+
+	var domains Domains
+	if err := decodeReport(data, "live_one_domains", &domains); err != nil {
+		return Team{}, false, err
+	}
+	for _, d := range domains.Data {
+		if owned(d.Cluster, d.Namespace) {
+			team.Domains = append(team.Domains, d)
+		}
+	}
+
+	// apply-live only applies the namespaces of the live cluster
+	var erroringNamespaces []NamespaceError
+	if err := decodeReport(data, "erroring_namespaces", &erroringNamespaces); err != nil {
+		return Team{}, false, err
+	}
+	for _, e := range erroringNamespaces {
+		if owned("live", e.Namespace) {
+			team.ErroringNamespaces = append(team.ErroringNamespaces, e)
+		}
+	}
+
+	return team, true, nil
+}
+
+// teamCSV is a row for each namespace of the team with its cost, usage and how many of its helm
+// releases are out of date, domains are still to migrate and whether it is failing to apply
+func teamCSV(data []byte) (csvTable, error) {
+	var team Team
+	if err := json.Unmarshal(data, &team); err != nil {
+		return csvTable{}, err
+	}
+
+	releases := make(map[string]int)
+	for _, h := range team.HelmReleases {
+		releases[h.Cluster+"/"+h.Namespace]++
+	}
+	domains := make(map[string]int)
+	for _, d := range team.Domains {
+		domains[d.Cluster+"/"+d.Namespace]++
+	}
+	erroring := make(map[string]bool)
+	for _, e := range team.ErroringNamespaces {
+		erroring["live/"+e.Namespace] = true
+	}
+
+	table := csvTable{header: []string{"team", "cluster", "namespace", "application", "business_unit", "slack_channel", "source_code", "cost",
+		"cpu_requested", "cpu_used", "memory_requested", "memory_used", "pods", "containers", "out_of_date_helm_releases", "live_one_domains", "erroring"}}
+	for _, ns := range team.Namespaces {
+		key := ns.Cluster + "/" + ns.Namespace
+		table.rows = append(table.rows, []string{team.Name, ns.Cluster, ns.Namespace, ns.Application, ns.BusinessUnit, ns.SlackChannel, ns.SourceCode, formatFloat(ns.Cost),
+			fmt.Sprint(ns.CPURequested), fmt.Sprint(ns.CPUUsed), fmt.Sprint(ns.MemoryRequested), fmt.Sprint(ns.MemoryUsed), fmt.Sprint(ns.Pods), fmt.Sprint(ns.Containers),
+			fmt.Sprint(releases[key]), fmt.Sprint(domains[key]), fmt.Sprint(erroring[key])})
+	}
+
+	return table, nil
+}
@@ -0,0 +1,95 @@
+package lib
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func teamReports() map[string][]byte {
+	return map[string][]byte{
+		"hosted_services": []byte(`{"namespace_details": [
+			{"Name": "ns-one", "Cluster": "live", "Application": "Tracker", "TeamName": "WebOps", "TeamSlackChannel": "#webops"},
+			{"Name": "ns-one", "Cluster": "live-2", "Application": "Tracker", "TeamName": "webops", "TeamSlackChannel": "#webops"},
+			{"Name": "ns-two", "Cluster": "live", "Application": "Payments", "TeamName": "finance-dev", "TeamSlackChannel": "#finance"}
+		]}`),
+		"namespace_costs": []byte(`{"namespace": {"ns-one": {"total": 12.5, "clusters": ["live", "live-2"]}, "ns-two": {"total": 3}}}`),
+		"namespace_usage": []byte(`{"data": [
+			{"Name": "ns-one", "Cluster": "live", "ContainerCount": 3, "Requested": {"cpu": 100, "memory": 256}, "Used": {"cpu": 20, "memory": 128, "pods": 2}},
+			{"Name": "ns-one", "Cluster": "live-2", "ContainerCount": 1, "Used": {"pods": 1}}
+		]}`),
+		"helm_releases": []byte(`{"clusters": [{"name": "live", "apps": [
+			{"name": "old", "namespace": "ns-one", "chart": "api-1.0.0", "installed_version": "1.0.0", "latest_version": "2.0.0"},
+			{"name": "current", "namespace": "ns-one", "chart": "api-2.0.0", "installed_version": "2.0.0", "latest_version": "2.0.0"},
+			{"name": "theirs", "namespace": "ns-two", "chart": "api-1.0.0", "installed_version": "1.0.0", "latest_version": "2.0.0"}
+		]}]}`),
+		"live_one_domains":    []byte(`{"live_one_domains": [{"cluster": "live", "namespace": "ns-one", "hostname": "tracker.apps.live-1.cloud-platform.service.justice.gov.uk"}]}`),
+		"erroring_namespaces": []byte(`[{"namespace": "ns-one", "error": "timeout", "build_id": 42}, {"namespace": "ns-two", "error": "quota", "build_id": 43}]`),
+	}
+}
+
+func Test_buildTeam(t *testing.T) {
+	data := teamReports()
+
+	if _, ok, _ := buildTeam("nobody", data); ok {
+		t.Error("buildTeam() of a team without namespaces returned a team")
+	}
+
+	team, ok, err := buildTeam("WEBOPS", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("buildTeam() didn't find the namespaces of the team, whatever its case")
+	}
+
+	var namespaces []string
+	for _, ns := range team.Namespaces {
+		namespaces = append(namespaces, ns.Cluster+"/"+ns.Namespace)
+	}
+	if want := []string{"live/ns-one", "live-2/ns-one"}; !reflect.DeepEqual(namespaces, want) {
+		t.Errorf("buildTeam() namespaces = %v, want %v", namespaces, want)
+	}
+	if want := []string{"#webops"}; !reflect.DeepEqual(team.SlackChannels, want) {
+		t.Errorf("buildTeam() slack channels = %v, want %v", team.SlackChannels, want)
+	}
+	if team.TotalCost != 12.5 {
+		t.Errorf("buildTeam() total cost = %v, want a namespace in two clusters charged once", team.TotalCost)
+	}
+	if want := (TeamUsage{CPURequested: 100, CPUUsed: 20, MemoryRequested: 256, MemoryUsed: 128, Pods: 3, Containers: 4}); team.Usage != want {
+		t.Errorf("buildTeam() usage = %+v, want %+v", team.Usage, want)
+	}
+	if len(team.HelmReleases) != 1 || team.HelmReleases[0].Name != "old" {
+		t.Errorf("buildTeam() helm releases = %+v, want only the out of date release of the team", team.HelmReleases)
+	}
+	if len(team.Domains) != 1 {
+		t.Errorf("buildTeam() domains = %+v, want 1", team.Domains)
+	}
+	if len(team.ErroringNamespaces) != 1 || team.ErroringNamespaces[0].BuildID != 42 {
+		t.Errorf("buildTeam() erroring namespaces = %+v, want ns-one", team.ErroringNamespaces)
+	}
+}
+
+func Test_teamCSV(t *testing.T) {
+	team, _, err := buildTeam("webops", teamReports())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(team)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := teamCSV(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"WebOps", "live", "ns-one", "Tracker", "", "#webops", "", "12.5", "100", "20", "256", "128", "2", "3", "1", "1", "true"},
+		{"WebOps", "live-2", "ns-one", "Tracker", "", "#webops", "", "0", "0", "0", "0", "0", "1", "1", "0", "0", "false"},
+	}
+	if !reflect.DeepEqual(table.rows, want) {
+		t.Errorf("teamCSV() = %v, want %v", table.rows, want)
+	}
+}
@@ -0,0 +1,55 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// reportSchema describes where the data of a report json is held and the type
+// the pages decode it into.
+type reportSchema struct {
+	field  string
+	target func() interface{}
+}
+
+// reportSchemas is keyed on the name of the report json in the hoodaw bucket
+var reportSchemas = map[string]reportSchema{
+	"certificate_expiry.json":          {field: "certificates", target: func() interface{} { return &CertificateExpiry{} }},
+	"domain_reachability.json":         {field: "domains", target: func() interface{} { return &DomainReachability{} }},
+	"erroring_namespaces_history.json": {field: "runs", target: func() interface{} { return &ErroringNamespacesHistory{} }},
+	"helm_releases.json":               {field: "clusters", target: func() interface{} { return &HelmReleases{} }},
+	"hosted_services.json":             {field: "namespace_details", target: func() interface{} { return &HostedServices{} }},
+	"infrastructure_deployments.json":  {field: "deployments", target: func() interface{} { return &InfrastructureDeployments{} }},
+	"live_one_domains.json":            {field: "live_one_domains", target: func() interface{} { return &Domains{} }},
+	"namespace_compliance.json":        {field: "namespace_compliance", target: func() interface{} { return &NamespaceCompliance{} }},
+	"namespace_costs.json":             {field: "namespace", target: func() interface{} { return &Costs{} }},
+	"namespace_usage.json":             {field: "data", target: func() interface{} { return &NamespaceUsage{} }},
+}
+
+// ValidateReport checks the report json stored under key has an updated_at timestamp,
+// contains its data and can be decoded into the types used by the pages.
+func ValidateReport(key string, data []byte) error {
+	schema, ok := reportSchemas[key]
+	if !ok {
+		return fmt.Errorf("unknown report %s", key)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("%s is not a json object: %w", key, err)
+	}
+
+	if _, ok := fields["updated_at"]; !ok {
+		return fmt.Errorf("%s is missing the updated_at timestamp", key)
+	}
+
+	if _, ok := fields[schema.field]; !ok {
+		return fmt.Errorf("%s is missing the %s field", key, schema.field)
+	}
+
+	if err := json.Unmarshal(data, schema.target()); err != nil {
+		return fmt.Errorf("%s does not match the page data: %w", key, err)
+	}
+
+	return nil
+}
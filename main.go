@@ -3,78 +3,51 @@ package main
 import (
 	"fmt"
 	"log"
-	"net/http"
-
-	lib "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/lib"
-	utils "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+	"os"
 )
 
+const usage = `hoodaw - Cloud Platform "How out of date are we" reports
+
+Usage:
+  hoodaw serve [flags]                 serve the report pages (default)
+  hoodaw report <name> [flags]         generate a report, e.g. hoodaw report hosted-services
+  hoodaw report list                   list the available reports
+  hoodaw validate [flags] <file>...    check report json files can be read by the web application
+
+Run "hoodaw <command> -h" for the flags of each command.
+`
+
+// The version and commit of the binary, set by the docker build with -ldflags "-X main.version=..."
 var (
-	bucket        = "cloud-platform-hoodaw-reports"
-	errorNsBucket = "cloud-platform-concourse-environments-live-reports"
+	version = "dev"
+	commit  = "unknown"
 )
 
 func main() {
-	client, err := utils.S3Client("eu-west-2")
-	if err != nil {
-		fmt.Println(err)
-	}
+	args := os.Args[1:]
 
-	for _, b := range []string{bucket, errorNsBucket} {
-		exists, err := utils.CheckBucketExists(client, b)
-		if err != nil {
-			fmt.Println(err)
-		}
-
-		if !exists {
-			fmt.Println("Bucket does not exist")
-		}
+	// With no subcommand, serve the web application as before
+	cmd := "serve"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
 	}
 
-	http.Handle("/static/",
-		http.StripPrefix("/static/",
-			http.FileServer(http.Dir("lib/static"))))
-
-	http.HandleFunc("/hosted_services", func(w http.ResponseWriter, r *http.Request) {
-		accept := r.Header.Get("Accept")
-		wantJson := accept == "application/json"
-		lib.HostedServicesPage(w, bucket, wantJson, client)
-	})
-
-	http.HandleFunc("/helm_whatup", func(w http.ResponseWriter, r *http.Request) {
-		accept := r.Header.Get("Accept")
-		wantJson := accept == "application/json"
-		lib.HelmReleasesPage(w, bucket, wantJson, client)
-	})
-
-	http.HandleFunc("/costs_by_namespace", func(w http.ResponseWriter, r *http.Request) {
-		accept := r.Header.Get("Accept")
-		wantJson := accept == "application/json"
-		lib.NamespaceCostsPage(w, bucket, wantJson, client)
-	})
-
-	http.HandleFunc("/erroring_namespaces", func(w http.ResponseWriter, r *http.Request) {
-		accept := r.Header.Get("Accept")
-		wantJson := accept == "application/json"
-		lib.ErroredNamespacesPage(w, errorNsBucket, wantJson, client)
-	})
-
-	http.HandleFunc("GET /namespace/{namespace}", func(w http.ResponseWriter, r *http.Request) {
-		namespace := r.PathValue("namespace")
-		accept := r.Header.Get("Accept")
-		wantJson := accept == "application/json"
-		lib.NamespaceUsagePage(w, bucket, namespace, wantJson, client)
-	})
-
-	http.HandleFunc("GET /live_one_domains", func(w http.ResponseWriter, r *http.Request) {
-		accept := r.Header.Get("Accept")
-		wantJson := accept == "application/json"
-		lib.LiveOneDomainsPage(w, bucket, wantJson, client)
-	})
+	var err error
+	switch cmd {
+	case "serve":
+		err = serve(args)
+	case "report":
+		err = report(args)
+	case "validate":
+		err = validate(args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		log.Fatalf("unknown command %q", cmd)
+	}
 
-	fmt.Println("Listening on port :8080 ...")
-	serverErr := http.ListenAndServe(":8080", nil)
-	if serverErr != nil {
-		log.Fatal("Error starting server: ", serverErr)
+	if err != nil {
+		log.Fatalln(err.Error())
 	}
 }
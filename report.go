@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	certificateexpiry "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/certificate-expiry"
+	domainreachability "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/domain-reachability"
+	erroringnamespaceshistory "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/erroring-namespaces-history"
+	helmreleases "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/helm-releases"
+	hostedservices "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/hosted-services"
+	infrastructuredeployments "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/infrastructure-deployments"
+	liveonedomains "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/live-one-domains"
+	namespacecompliance "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/namespace-compliance"
+	namespacecosts "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/namespace-costs"
+	namespaceusage "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/namespace-usage"
+	utils "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+)
+
+// reports returns all the reports which can be run with `hoodaw report <name>`
+func reports() []utils.Report {
+	return []utils.Report{
+		certificateexpiry.New(),
+		domainreachability.New(),
+		erroringnamespaceshistory.New(),
+		helmreleases.New(),
+		hostedservices.New(),
+		infrastructuredeployments.New(),
+		liveonedomains.New(),
+		namespacecompliance.New(),
+		namespacecosts.New(),
+		namespaceusage.New(),
+	}
+}
+
+// report generates the named report and writes the json to the chosen output
+func report(args []string) error {
+	if len(args) == 0 {
+		return errors.New("a report name is required, run \"hoodaw report list\" to see the available reports")
+	}
+
+	name, args := args[0], args[1:]
+
+	if name == "list" {
+		for _, r := range reports() {
+			fmt.Printf("%-30s %s\n", r.Name(), r.Key())
+		}
+		return nil
+	}
+
+	var rep utils.Report
+	for _, r := range reports() {
+		if r.Name() == name {
+			rep = r
+		}
+	}
+
+	if rep == nil {
+		return fmt.Errorf("unknown report %q, run \"hoodaw report list\" to see the available reports", name)
+	}
+
+	fs := flag.NewFlagSet("report "+name, flag.ExitOnError)
+	hoodawBucket := fs.String("hoodaw-bucket", os.Getenv("HOODAW_BUCKET"), "AWS S3 bucket for hoodaw json reports")
+	output := fs.String("output", "s3", "Comma separated list of where to write the report json: \"s3\" for the hoodaw bucket, s3://bucket/key, \"stdout\", an http(s) ingest URL, a .json file or a local directory")
+	apiKey := fs.String("api-key", os.Getenv("HOODAW_API_KEY"), "API key sent when the output is an http(s) ingest URL")
+	dryRun := fs.Bool("dry-run", false, "Generate the report and print a summary of what would be written instead of writing it")
+	rep.AddFlags(fs)
+	fs.Parse(args)
+
+	if r, ok := rep.(utils.HoodawReader); ok {
+		r.SetHoodawBucket(*hoodawBucket)
+	}
+
+	var outputs []utils.Output
+	for _, o := range strings.Split(*output, ",") {
+		out, err := utils.ParseOutput(strings.TrimSpace(o), *hoodawBucket, rep.Key(), *apiKey)
+		if err != nil {
+			return err
+		}
+		outputs = append(outputs, out)
+	}
+
+	// the outputs are checked first so a report doesn't run for a long time to then be lost
+	if !*dryRun {
+		for _, out := range outputs {
+			if err := out.Check(); err != nil {
+				return fmt.Errorf("unable to write %s report to %s: %w", name, out, err)
+			}
+		}
+	}
+
+	data, err := rep.Generate()
+	if err != nil {
+		return fmt.Errorf("failed to generate %s report: %w", name, err)
+	}
+
+	if *dryRun {
+		for _, out := range outputs {
+			fmt.Printf("dry run: would write %s report to %s\n", name, out)
+		}
+		fmt.Println(utils.SummariseReport(data))
+		return nil
+	}
+
+	return writeOutputs(name, outputs, data)
+}
+
+// writeOutputs writes the report to every output, even when one fails, and fails with all their
+// errors so the job fails when any output wasn't written
+func writeOutputs(name string, outputs []utils.Output, data []byte) error {
+	var errs []error
+	for _, out := range outputs {
+		if err := out.Write(data); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write %s report to %s: %w", name, out, err))
+			continue
+		}
+
+		log.Printf("Written %s report to %s\n", name, out)
+	}
+
+	return errors.Join(errs...)
+}
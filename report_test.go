@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+)
+
+// fakeOutput stands in for the hoodaw bucket, keeping what is written to it
+type fakeOutput struct {
+	written []byte
+}
+
+func (o *fakeOutput) Check() error            { return nil }
+func (o *fakeOutput) Write(data []byte) error { o.written = data; return nil }
+func (o *fakeOutput) String() string          { return "s3://hoodaw/infrastructure_deployments.json" }
+
+func Test_writeOutputs_failedPost(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer api.Close()
+
+	s3 := &fakeOutput{}
+	outputs := []utils.Output{s3, &utils.HTTPOutput{URL: api.URL + "/infra_deployments", APIKey: "wrong"}}
+
+	err := writeOutputs("infrastructure-deployments", outputs, []byte(`{"deployments": []}`))
+	if err == nil {
+		t.Fatal("writeOutputs() didn't fail when the post to the API failed")
+	}
+	if !strings.Contains(err.Error(), "/infra_deployments") || !strings.Contains(err.Error(), "403") {
+		t.Errorf("writeOutputs() error = %v, want the failed post", err)
+	}
+	if string(s3.written) != `{"deployments": []}` {
+		t.Errorf("writeOutputs() didn't write to s3 before the failed post, wrote %q", s3.written)
+	}
+}
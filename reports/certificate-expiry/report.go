@@ -0,0 +1,273 @@
+package certificateexpiry
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// certificateResource is the cert-manager Certificate custom resource
+var certificateResource = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+
+// resourceMap is used to store both string:string and string:[]Certificate key
+// value pairs. As per the requirements of the HOODAW API.
+type resourceMap map[string]interface{}
+
+// Certificate is the certificate served for a host, read from the TLS secret of an ingress or,
+// for hosts without an ingress, the status of a cert-manager Certificate. Error is set when
+// the certificate couldn't be read.
+type Certificate struct {
+	Cluster     string
+	Namespace   string
+	Host        string
+	Ingress     string
+	Secret      string
+	Certificate string
+	Issuer      string
+	IssuerRef   string
+	SANs        []string
+	NotAfter    string
+	Error       string
+}
+
+// Report lists the certificates of the ingress hosts in each cluster with when they expire.
+type Report struct {
+	bucket      string
+	contexts    utils.Contexts
+	kubeconfig  string
+	kubeCfgPath string
+	region      string
+}
+
+// New returns the certificate-expiry report
+func New() *Report {
+	return &Report{}
+}
+
+func (r *Report) Name() string { return "certificate-expiry" }
+
+func (r *Report) Key() string { return "certificate_expiry.json" }
+
+func (r *Report) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&r.bucket, "bucket", os.Getenv("KUBECONFIG_S3_BUCKET"), "AWS S3 bucket for kubeconfig")
+	r.contexts = utils.Contexts{"live.cloud-platform.service.justice.gov.uk"}
+	fs.Var(&r.contexts, "context", "Comma separated list of Kubernetes contexts specified in kubeconfig")
+	fs.StringVar(&r.kubeconfig, "kubeconfig", "kubeconfig", "Name of kubeconfig file in S3 bucket")
+	fs.StringVar(&r.kubeCfgPath, "kubeCfgPath", os.Getenv("KUBECONFIG"), "Path of the kube config file, used when no bucket is set")
+	fs.StringVar(&r.region, "region", os.Getenv("AWS_REGION"), "AWS Region")
+}
+
+func (r *Report) Generate() ([]byte, error) {
+	certificates := make([]Certificate, 0)
+
+	for _, ctx := range r.contexts {
+		cluster := utils.ClusterName(ctx)
+
+		config, err := utils.KubeConfig(r.bucket, r.kubeconfig, r.region, r.kubeCfgPath, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to authenticate to the %s cluster: %w", cluster, err)
+		}
+
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		dynamicClient, err := dynamic.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		certs, err := clusterCertificates(cluster, clientset, dynamicClient)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the certificates of the %s cluster: %w", cluster, err)
+		}
+
+		certificates = append(certificates, certs...)
+	}
+
+	return buildJsonMap(certificates)
+}
+
+// clusterCertificates returns the certificate of each TLS host of the ingresses in the cluster,
+// with the cert-manager Certificate which issues its secret, followed by the hosts of the
+// cert-manager Certificates which aren't used by an ingress
+func clusterCertificates(cluster string, clientset kubernetes.Interface, dynamicClient dynamic.Interface) ([]Certificate, error) {
+	ingresses, err := clientset.NetworkingV1().Ingresses("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	managed, err := certManagerCertificates(dynamicClient)
+	if err != nil {
+		return nil, err
+	}
+
+	certificates := make([]Certificate, 0)
+	// hosts holds namespace/host of the ingress hosts already listed
+	hosts := make(map[string]bool)
+	// secrets caches the parsed certificate of each namespace/secret
+	secrets := make(map[string]Certificate)
+
+	for _, ing := range ingresses.Items {
+		for _, tls := range ing.Spec.TLS {
+			// hosts without a secret are served the default certificate of the ingress controller
+			if tls.SecretName == "" {
+				continue
+			}
+
+			key := ing.Namespace + "/" + tls.SecretName
+			cert, ok := secrets[key]
+			if !ok {
+				cert = secretCertificate(clientset, ing.Namespace, tls.SecretName)
+				if m, ok := managed[key]; ok {
+					cert.Certificate = m.Certificate
+					cert.IssuerRef = m.IssuerRef
+				}
+				secrets[key] = cert
+			}
+
+			for _, host := range tls.Hosts {
+				c := cert
+				c.Cluster = cluster
+				c.Namespace = ing.Namespace
+				c.Host = host
+				c.Ingress = ing.Name
+				certificates = append(certificates, c)
+				hosts[ing.Namespace+"/"+host] = true
+			}
+		}
+	}
+
+	for _, m := range managed {
+		for _, host := range m.SANs {
+			if hosts[m.Namespace+"/"+host] {
+				continue
+			}
+
+			c := m
+			c.Cluster = cluster
+			c.Host = host
+			certificates = append(certificates, c)
+		}
+	}
+
+	sort.SliceStable(certificates, func(i, j int) bool {
+		if certificates[i].Namespace != certificates[j].Namespace {
+			return certificates[i].Namespace < certificates[j].Namespace
+		}
+		return certificates[i].Host < certificates[j].Host
+	})
+
+	return certificates, nil
+}
+
+// secretCertificate reads the leaf certificate from the tls.crt of a TLS secret
+func secretCertificate(clientset kubernetes.Interface, namespace, name string) Certificate {
+	cert := Certificate{Secret: name}
+
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		cert.Error = fmt.Sprintf("unable to read secret: %s", err)
+		return cert
+	}
+
+	x509Cert, err := parseCertificate(secret.Data["tls.crt"])
+	if err != nil {
+		cert.Error = err.Error()
+		return cert
+	}
+
+	cert.Issuer = x509Cert.Issuer.CommonName
+	if cert.Issuer == "" && len(x509Cert.Issuer.Organization) > 0 {
+		cert.Issuer = x509Cert.Issuer.Organization[0]
+	}
+	cert.SANs = x509Cert.DNSNames
+	cert.NotAfter = x509Cert.NotAfter.UTC().Format(time.RFC3339)
+
+	return cert
+}
+
+// parseCertificate returns the first certificate of a PEM bundle, which is the leaf certificate
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	for len(data) > 0 {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+
+	return nil, errors.New("no certificate found in tls.crt")
+}
+
+// certManagerCertificates returns the cert-manager Certificates keyed on namespace/secret name.
+// Clusters without cert-manager have none.
+func certManagerCertificates(dynamicClient dynamic.Interface) (map[string]Certificate, error) {
+	certificates := make(map[string]Certificate)
+
+	list, err := dynamicClient.Resource(certificateResource).Namespace("").List(context.TODO(), metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		return certificates, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range list.Items {
+		secretName, _, _ := unstructured.NestedString(item.Object, "spec", "secretName")
+		dnsNames, _, _ := unstructured.NestedStringSlice(item.Object, "spec", "dnsNames")
+		issuerKind, _, _ := unstructured.NestedString(item.Object, "spec", "issuerRef", "kind")
+		issuerName, _, _ := unstructured.NestedString(item.Object, "spec", "issuerRef", "name")
+		notAfter, _, _ := unstructured.NestedString(item.Object, "status", "notAfter")
+
+		if issuerKind == "" {
+			issuerKind = "Issuer"
+		}
+
+		certificates[item.GetNamespace()+"/"+secretName] = Certificate{
+			Namespace:   item.GetNamespace(),
+			Secret:      secretName,
+			Certificate: item.GetName(),
+			IssuerRef:   issuerKind + "/" + issuerName,
+			SANs:        dnsNames,
+			NotAfter:    notAfter,
+		}
+	}
+
+	return certificates, nil
+}
+
+// buildJsonMap takes the certificates and return a json encoded map
+func buildJsonMap(certificates []Certificate) ([]byte, error) {
+	jsonMap := resourceMap{
+		"updated_at":   time.Now().Format("2006-01-2 15:4:5 UTC"),
+		"certificates": certificates,
+	}
+
+	jsonStr, err := json.Marshal(jsonMap)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonStr, nil
+}
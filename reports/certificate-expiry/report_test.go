@@ -0,0 +1,136 @@
+package certificateexpiry
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testCertificate returns a PEM encoded self signed certificate for the hosts,
+// its issuer is the first host
+func testCertificate(t *testing.T, notAfter time.Time, hosts ...string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		DNSNames:     hosts,
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func Test_clusterCertificates(t *testing.T) {
+	notAfter := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	clientset := fake.NewSimpleClientset(
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "app"},
+			Spec: networkingv1.IngressSpec{
+				TLS: []networkingv1.IngressTLS{
+					{Hosts: []string{"app.service.justice.gov.uk"}, SecretName: "app-tls"},
+					{Hosts: []string{"missing.service.justice.gov.uk"}, SecretName: "missing-tls"},
+					{Hosts: []string{"default.service.justice.gov.uk"}},
+				},
+			},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "app-tls"},
+			Data:       map[string][]byte{"tls.crt": testCertificate(t, notAfter, "app.service.justice.gov.uk")},
+		},
+	)
+
+	certificate := func(namespace, name, secret string, dnsNames []interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "cert-manager.io/v1",
+			"kind":       "Certificate",
+			"metadata":   map[string]interface{}{"namespace": namespace, "name": name},
+			"spec": map[string]interface{}{
+				"secretName": secret,
+				"dnsNames":   dnsNames,
+				"issuerRef":  map[string]interface{}{"kind": "ClusterIssuer", "name": "letsencrypt-production"},
+			},
+			"status": map[string]interface{}{"notAfter": "2025-07-01T00:00:00Z"},
+		}}
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{certificateResource: "CertificateList"},
+		certificate("ns1", "app", "app-tls", []interface{}{"app.service.justice.gov.uk"}),
+		certificate("ns2", "api", "api-tls", []interface{}{"api.service.justice.gov.uk"}),
+	)
+
+	got, err := clusterCertificates("live", clientset, dynamicClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Certificate{
+		{
+			Cluster:     "live",
+			Namespace:   "ns1",
+			Host:        "app.service.justice.gov.uk",
+			Ingress:     "app",
+			Secret:      "app-tls",
+			Certificate: "app",
+			Issuer:      "app.service.justice.gov.uk",
+			IssuerRef:   "ClusterIssuer/letsencrypt-production",
+			SANs:        []string{"app.service.justice.gov.uk"},
+			NotAfter:    "2025-06-01T00:00:00Z",
+		},
+		{
+			Cluster:   "live",
+			Namespace: "ns1",
+			Host:      "missing.service.justice.gov.uk",
+			Ingress:   "app",
+			Secret:    "missing-tls",
+			Error:     `unable to read secret: secrets "missing-tls" not found`,
+		},
+		{
+			Cluster:     "live",
+			Namespace:   "ns2",
+			Host:        "api.service.justice.gov.uk",
+			Secret:      "api-tls",
+			Certificate: "api",
+			IssuerRef:   "ClusterIssuer/letsencrypt-production",
+			SANs:        []string{"api.service.justice.gov.uk"},
+			NotAfter:    "2025-07-01T00:00:00Z",
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("clusterCertificates() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_parseCertificate(t *testing.T) {
+	if _, err := parseCertificate([]byte("not a certificate")); err == nil {
+		t.Error("parseCertificate() expected an error for data without a certificate")
+	}
+}
@@ -0,0 +1,273 @@
+package domainreachability
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+)
+
+// The status of a domain after it has been checked
+const (
+	StatusOK          = "ok"
+	StatusDangling    = "dangling"
+	StatusDNSError    = "dns_error"
+	StatusUnreachable = "unreachable"
+	StatusServerError = "server_error"
+)
+
+// resourceMap is used to store both string:string and string:[]Domain key
+// value pairs. As per the requirements of the HOODAW API.
+type resourceMap map[string]interface{}
+
+// Domain is the result of resolving and requesting a hostname of a namespace. Target is the
+// canonical name the hostname is a CNAME of, empty when it resolves directly to Addresses.
+type Domain struct {
+	Cluster    string
+	Namespace  string
+	Hostname   string
+	Target     string
+	Addresses  []string
+	StatusCode int
+	LatencyMs  int64
+	Status     string
+	Error      string
+}
+
+// hostedServices is the part of the hosted services report the domains are read from
+type hostedServices struct {
+	Namespaces []struct {
+		Name        string   `json:"Name"`
+		Cluster     string   `json:"Cluster"`
+		DomainNames []string `json:"DomainNames"`
+	} `json:"namespace_details"`
+}
+
+// Report resolves the domain names of the hosted services and checks they respond over HTTPS,
+// flagging the dangling domains which no longer resolve.
+type Report struct {
+	source       string
+	hoodawBucket string
+	resolver     string
+	dnsTimeout   time.Duration
+	httpTimeout  time.Duration
+	concurrency  int
+}
+
+// New returns the domain-reachability report
+func New() *Report {
+	return &Report{}
+}
+
+func (r *Report) Name() string { return "domain-reachability" }
+
+func (r *Report) Key() string { return "domain_reachability.json" }
+
+func (r *Report) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&r.source, "hosted-services", "", "Hosted services report to read the domains from: s3://bucket/key, an http(s) URL or a file (default the report in the --hoodaw-bucket)")
+	fs.StringVar(&r.resolver, "resolver", "", "DNS server to resolve the domains with as host:port, the system resolver when empty")
+	fs.DurationVar(&r.dnsTimeout, "dns-timeout", 5*time.Second, "Timeout of each DNS lookup")
+	fs.DurationVar(&r.httpTimeout, "http-timeout", 10*time.Second, "Timeout of each HTTPS request")
+	fs.IntVar(&r.concurrency, "concurrency", 20, "Number of domains to check at once")
+}
+
+var _ utils.HoodawReader = (*Report)(nil)
+
+// SetHoodawBucket sets the bucket the hosted services report is read from by default
+func (r *Report) SetHoodawBucket(bucket string) { r.hoodawBucket = bucket }
+
+func (r *Report) Generate() ([]byte, error) {
+	if r.concurrency < 1 {
+		return nil, fmt.Errorf("concurrency must be at least 1, got %d", r.concurrency)
+	}
+
+	source, err := utils.HoodawSource(r.source, r.hoodawBucket, "hosted_services.json", "hosted-services")
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := utils.ReadSource(source)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the hosted services from %s: %w", source, err)
+	}
+
+	domains, err := hostedDomains(data)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newChecker(r.resolver, r.dnsTimeout, r.httpTimeout)
+	c.checkAll(domains, r.concurrency)
+
+	return buildJsonMap(domains)
+}
+
+// hostedDomains returns a domain to check for each domain name of the hosted services, sorted by
+// hostname. Wildcard hosts are left out as they can't be resolved.
+func hostedDomains(data []byte) ([]Domain, error) {
+	var hs hostedServices
+	if err := json.Unmarshal(data, &hs); err != nil {
+		return nil, fmt.Errorf("unable to decode the hosted services: %w", err)
+	}
+
+	domains := make([]Domain, 0)
+	for _, ns := range hs.Namespaces {
+		for _, host := range ns.DomainNames {
+			if host == "" || strings.HasPrefix(host, "*") {
+				continue
+			}
+			domains = append(domains, Domain{Cluster: ns.Cluster, Namespace: ns.Name, Hostname: host})
+		}
+	}
+
+	sort.SliceStable(domains, func(i, j int) bool {
+		return domains[i].Hostname < domains[j].Hostname
+	})
+
+	return domains, nil
+}
+
+// checker resolves domains with its resolver and requests them with its client, which dials the
+// addresses given by the same resolver. port is the port of the HTTPS requests.
+type checker struct {
+	resolver   *net.Resolver
+	client     *http.Client
+	dnsTimeout time.Duration
+	port       string
+}
+
+// newChecker returns a checker using the DNS server at resolverAddr, or the system resolver
+// when it is empty
+func newChecker(resolverAddr string, dnsTimeout, httpTimeout time.Duration) *checker {
+	resolver := net.DefaultResolver
+	if resolverAddr != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				d := net.Dialer{Timeout: dnsTimeout}
+				return d.DialContext(ctx, network, resolverAddr)
+			},
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: httpTimeout, Resolver: resolver}
+
+	return &checker{
+		resolver:   resolver,
+		dnsTimeout: dnsTimeout,
+		port:       "443",
+		client: &http.Client{
+			Timeout: httpTimeout,
+			Transport: &http.Transport{
+				DialContext:       dialer.DialContext,
+				TLSClientConfig:   &tls.Config{},
+				DisableKeepAlives: true,
+			},
+			// the status of the domain itself is wanted, not of wherever it redirects to
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// checkAll checks the domains in place, at most concurrency at a time
+func (c *checker) checkAll(domains []Domain, concurrency int) {
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i := range domains {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(d *Domain) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			c.check(d)
+		}(&domains[i])
+	}
+
+	wg.Wait()
+}
+
+// check resolves the hostname of the domain then makes a HEAD request to it over HTTPS,
+// recording its DNS target, status code and latency
+func (c *checker) check(d *Domain) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.dnsTimeout)
+	defer cancel()
+
+	// the trailing dot stops the resolver trying the search domains of the host it runs on
+	fqdn := strings.TrimSuffix(d.Hostname, ".") + "."
+
+	addresses, err := c.resolver.LookupHost(ctx, fqdn)
+	if err != nil {
+		d.Status, d.Error = StatusDNSError, err.Error()
+
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			d.Status = StatusDangling
+		}
+		return
+	}
+	sort.Strings(addresses)
+	d.Addresses = addresses
+
+	if cname, err := c.resolver.LookupCNAME(ctx, fqdn); err == nil && cname != fqdn {
+		d.Target = strings.TrimSuffix(cname, ".")
+	}
+
+	url := "https://" + d.Hostname + "/"
+	if c.port != "443" {
+		url = "https://" + net.JoinHostPort(d.Hostname, c.port) + "/"
+	}
+
+	req, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		d.Status, d.Error = StatusUnreachable, err.Error()
+		return
+	}
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	d.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		d.Status, d.Error = StatusUnreachable, err.Error()
+		return
+	}
+	resp.Body.Close()
+
+	d.StatusCode = resp.StatusCode
+	d.Status = StatusOK
+	if resp.StatusCode >= 500 {
+		d.Status = StatusServerError
+	}
+}
+
+// buildJsonMap returns the domains with the number which are dangling
+func buildJsonMap(domains []Domain) ([]byte, error) {
+	dangling := 0
+	for _, d := range domains {
+		if d.Status == StatusDangling {
+			dangling++
+		}
+	}
+
+	jsonMap := resourceMap{
+		"updated_at": time.Now().Format("2006-01-2 15:4:5 UTC"),
+		"domains":    domains,
+		"dangling":   dangling,
+	}
+
+	return json.Marshal(jsonMap)
+}
@@ -0,0 +1,165 @@
+package domainreachability
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// stubDNS answers A queries over UDP from its records, a name is either a CNAME of another
+// name or has an IPv4 address. Any other name doesn't exist.
+type stubDNS struct {
+	cnames    map[string]string
+	addresses map[string][4]byte
+}
+
+// serve starts the stub on a local port and returns its address
+func (s *stubDNS) serve(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var msg dnsmessage.Message
+			if err := msg.Unpack(buf[:n]); err != nil || len(msg.Questions) == 0 {
+				continue
+			}
+
+			reply := s.answer(msg)
+			packed, err := reply.Pack()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(packed, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func (s *stubDNS) answer(query dnsmessage.Message) dnsmessage.Message {
+	q := query.Questions[0]
+	reply := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true, RecursionAvailable: true},
+		Questions: query.Questions,
+	}
+
+	name := q.Name.String()
+	for {
+		if target, ok := s.cnames[name]; ok {
+			reply.Answers = append(reply.Answers, dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET, TTL: 60},
+				Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(target)},
+			})
+			name = target
+			continue
+		}
+
+		if address, ok := s.addresses[name]; ok {
+			if q.Type == dnsmessage.TypeA {
+				reply.Answers = append(reply.Answers, dnsmessage.Resource{
+					Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: address},
+				})
+			}
+			return reply
+		}
+
+		reply.Header.RCode = dnsmessage.RCodeNameError
+		return reply
+	}
+}
+
+func Test_checker_checkAll(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("got a %s request, want HEAD", r.Method)
+		}
+		if host, _, _ := net.SplitHostPort(r.Host); host == "www.example.com" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	dns := &stubDNS{
+		cnames: map[string]string{
+			"www.example.com.":  "lb.example.com.",
+			"gone.example.com.": "deleted-lb.example.com.",
+		},
+		addresses: map[string][4]byte{
+			"example.com.":        {127, 0, 0, 1},
+			"lb.example.com.":     {127, 0, 0, 1},
+			"closed.example.com.": {127, 0, 0, 2},
+		},
+	}
+
+	c := newChecker(dns.serve(t), 2*time.Second, 2*time.Second)
+	c.client.Transport.(*http.Transport).TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig
+	_, c.port, _ = net.SplitHostPort(server.Listener.Addr().String())
+
+	domains := []Domain{
+		{Hostname: "closed.example.com"},
+		{Hostname: "example.com"},
+		{Hostname: "gone.example.com"},
+		{Hostname: "missing.example.com"},
+		{Hostname: "www.example.com"},
+	}
+
+	c.checkAll(domains, 2)
+
+	want := []Domain{
+		{Hostname: "closed.example.com", Addresses: []string{"127.0.0.2"}, Status: StatusUnreachable},
+		{Hostname: "example.com", Addresses: []string{"127.0.0.1"}, StatusCode: http.StatusOK, Status: StatusOK},
+		{Hostname: "gone.example.com", Status: StatusDangling},
+		{Hostname: "missing.example.com", Status: StatusDangling},
+		{Hostname: "www.example.com", Target: "lb.example.com", Addresses: []string{"127.0.0.1"}, StatusCode: http.StatusServiceUnavailable, Status: StatusServerError},
+	}
+
+	for i := range domains {
+		if (domains[i].Error != "") != (domains[i].Status == StatusUnreachable || domains[i].Status == StatusDangling) {
+			t.Errorf("%s has error %q with status %s", domains[i].Hostname, domains[i].Error, domains[i].Status)
+		}
+		// errors and latency depend on the host the test runs on
+		domains[i].Error, domains[i].LatencyMs = "", 0
+	}
+
+	if !reflect.DeepEqual(domains, want) {
+		t.Errorf("checkAll() = %+v, want %+v", domains, want)
+	}
+}
+
+func Test_hostedDomains(t *testing.T) {
+	data := []byte(`{"namespace_details": [
+		{"Name": "ns1", "Cluster": "live", "DomainNames": ["b.service.justice.gov.uk", "*.apps.service.justice.gov.uk"]},
+		{"Name": "ns2", "Cluster": "live", "DomainNames": ["a.service.justice.gov.uk"]},
+		{"Name": "ns3", "Cluster": "live", "DomainNames": null}
+	]}`)
+
+	want := []Domain{
+		{Cluster: "live", Namespace: "ns2", Hostname: "a.service.justice.gov.uk"},
+		{Cluster: "live", Namespace: "ns1", Hostname: "b.service.justice.gov.uk"},
+	}
+
+	got, err := hostedDomains(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hostedDomains() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,203 @@
+package erroringnamespaceshistory
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+)
+
+// The status of a namespace in the latest run
+const (
+	StatusNew     = "new"
+	StatusFailing = "failing"
+	StatusFixed   = "fixed"
+)
+
+// erroringNamespacesKey is the report of the namespaces which failed in the last apply-live run
+const erroringNamespacesKey = "apply-live/gathered-namespaces-errors.json"
+
+// resourceMap is used to store both string:string and string:[]Run key
+// value pairs. As per the requirements of the HOODAW API.
+type resourceMap map[string]interface{}
+
+// NamespaceError is a namespace which failed to apply, as written by the apply-live pipeline
+type NamespaceError = hoodaw.NamespaceError
+
+// Run is the namespaces which failed in an apply-live run, identified by when its report was written
+type Run = hoodaw.ErrorRun
+
+// Trend is how long a namespace has been failing, or when it was fixed
+type Trend = hoodaw.ErrorTrend
+
+// Report keeps the history of the namespaces failing in each apply-live run, as the pipeline
+// overwrites its report every run, and works out which namespaces are newly failing or fixed.
+type Report struct {
+	errorNsBucket string
+	hoodawBucket  string
+	history       string
+	maxRuns       int
+	fixedRuns     int
+}
+
+// New returns the erroring-namespaces-history report
+func New() *Report {
+	return &Report{}
+}
+
+func (r *Report) Name() string { return "erroring-namespaces-history" }
+
+func (r *Report) Key() string { return "erroring_namespaces_history.json" }
+
+func (r *Report) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&r.errorNsBucket, "error-ns-bucket", "cloud-platform-concourse-environments-live-reports", "AWS S3 bucket for the apply-live erroring namespaces report")
+	fs.StringVar(&r.history, "history", "", "Previous history to add the latest run to: s3://bucket/key, an http(s) URL or a file (default the report in the --hoodaw-bucket)")
+	fs.IntVar(&r.maxRuns, "max-runs", 100, "Number of runs to keep in the history")
+	fs.IntVar(&r.fixedRuns, "fixed-runs", 3, "Number of runs a namespace is shown as fixed for after it stops failing")
+}
+
+var _ utils.HoodawReader = (*Report)(nil)
+
+// SetHoodawBucket sets the bucket the history is written to, and so read back from by default
+func (r *Report) SetHoodawBucket(bucket string) { r.hoodawBucket = bucket }
+
+func (r *Report) Generate() ([]byte, error) {
+	if r.maxRuns < 1 {
+		return nil, fmt.Errorf("max-runs must be at least 1, got %d", r.maxRuns)
+	}
+
+	source, err := utils.HoodawSource(r.history, r.hoodawBucket, r.Key(), "history")
+	if err != nil {
+		return nil, err
+	}
+
+	var previous hoodaw.ErroringNamespacesHistory
+	data, err := utils.ReadSource(source)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		log.Printf("No history at %s, starting a new one\n", source)
+	case err != nil:
+		return nil, fmt.Errorf("unable to read the history from %s: %w", source, err)
+	default:
+		if err := json.Unmarshal(data, &previous); err != nil {
+			return nil, fmt.Errorf("unable to decode the history from %s: %w", source, err)
+		}
+	}
+
+	client, err := utils.S3Client("eu-west-2")
+	if err != nil {
+		return nil, err
+	}
+
+	data, lastModified, err := utils.ImportS3File(client, r.errorNsBucket, erroringNamespacesKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s from %s: %w", erroringNamespacesKey, r.errorNsBucket, err)
+	}
+
+	latest := Run{UpdatedAt: lastModified}
+	if err := json.Unmarshal(data, &latest.Namespaces); err != nil {
+		return nil, fmt.Errorf("unable to decode %s: %w", erroringNamespacesKey, err)
+	}
+
+	runs := addRun(previous.Runs, latest, r.maxRuns)
+
+	return buildJsonMap(runs, trends(runs, r.fixedRuns))
+}
+
+// addRun adds the latest run to the front of the runs, keeping at most maxRuns. The run isn't
+// added again when the pipeline hasn't written a new report since the last one.
+func addRun(runs []Run, latest Run, maxRuns int) []Run {
+	if latest.Namespaces == nil {
+		latest.Namespaces = []NamespaceError{}
+	}
+
+	if len(runs) == 0 || runs[0].UpdatedAt != latest.UpdatedAt {
+		runs = append([]Run{latest}, runs...)
+	}
+
+	if len(runs) > maxRuns {
+		runs = runs[:maxRuns]
+	}
+
+	return runs
+}
+
+// trends returns the trend of each namespace failing in the latest run, and of those which were
+// fixed within the last fixedRuns runs, from the runs newest first. A namespace is only new once
+// there's a previous run to compare with.
+func trends(runs []Run, fixedRuns int) []Trend {
+	failing := make([]map[string]NamespaceError, len(runs))
+	for i, run := range runs {
+		failing[i] = make(map[string]NamespaceError)
+		for _, e := range run.Namespaces {
+			failing[i][e.Namespace] = e
+		}
+	}
+
+	// streak returns the trend of the failures of namespace ending in the run at index from
+	streak := func(namespace string, from int) Trend {
+		e := failing[from][namespace]
+		t := Trend{Namespace: namespace, LastSeen: runs[from].UpdatedAt, Error: e.Error, BuildID: e.BuildID}
+		for i := from; i < len(runs); i++ {
+			if _, ok := failing[i][namespace]; !ok {
+				break
+			}
+			t.ConsecutiveFailures++
+			t.FirstSeen = runs[i].UpdatedAt
+		}
+		return t
+	}
+
+	result := make([]Trend, 0)
+	if len(runs) == 0 {
+		return result
+	}
+
+	for namespace := range failing[0] {
+		t := streak(namespace, 0)
+		t.Status = StatusFailing
+		if t.ConsecutiveFailures == 1 && len(runs) > 1 {
+			t.Status = StatusNew
+		}
+		result = append(result, t)
+	}
+
+	fixed := make(map[string]bool)
+	for i := 1; i <= fixedRuns && i < len(runs); i++ {
+		for namespace := range failing[i] {
+			if _, ok := failing[0][namespace]; ok || fixed[namespace] {
+				continue
+			}
+			fixed[namespace] = true
+
+			t := streak(namespace, i)
+			t.Status = StatusFixed
+			t.FixedAt = runs[i-1].UpdatedAt
+			result = append(result, t)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Namespace < result[j].Namespace
+	})
+
+	return result
+}
+
+// buildJsonMap returns the runs, newest first, with the trend of each namespace
+func buildJsonMap(runs []Run, trends []Trend) ([]byte, error) {
+	jsonMap := resourceMap{
+		"updated_at": time.Now().Format("2006-01-2 15:4:5 UTC"),
+		"runs":       runs,
+		"trends":     trends,
+	}
+
+	return json.Marshal(jsonMap)
+}
@@ -0,0 +1,54 @@
+package erroringnamespaceshistory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_addRun(t *testing.T) {
+	runs := []Run{{UpdatedAt: "2"}, {UpdatedAt: "1"}}
+
+	tests := []struct {
+		name    string
+		latest  Run
+		maxRuns int
+		want    []Run
+	}{
+		{"new run", Run{UpdatedAt: "3"}, 10, []Run{{UpdatedAt: "3", Namespaces: []NamespaceError{}}, {UpdatedAt: "2"}, {UpdatedAt: "1"}}},
+		{"same run", Run{UpdatedAt: "2"}, 10, runs},
+		{"trimmed", Run{UpdatedAt: "3"}, 2, []Run{{UpdatedAt: "3", Namespaces: []NamespaceError{}}, {UpdatedAt: "2"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addRun(runs, tt.latest, tt.maxRuns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("addRun() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_trends(t *testing.T) {
+	runs := []Run{
+		{UpdatedAt: "run5", Namespaces: []NamespaceError{{Namespace: "new", Error: "boom", BuildID: 5}, {Namespace: "old", Error: "still", BuildID: 5}}},
+		{UpdatedAt: "run4", Namespaces: []NamespaceError{{Namespace: "old", Error: "still", BuildID: 4}, {Namespace: "fixed", Error: "was", BuildID: 4}}},
+		{UpdatedAt: "run3", Namespaces: []NamespaceError{{Namespace: "old", Error: "still", BuildID: 3}, {Namespace: "fixed", Error: "was", BuildID: 3}}},
+		{UpdatedAt: "run2", Namespaces: []NamespaceError{{Namespace: "long-fixed", Error: "gone", BuildID: 2}}},
+		{UpdatedAt: "run1", Namespaces: []NamespaceError{{Namespace: "old", Error: "first", BuildID: 1}}},
+	}
+
+	want := []Trend{
+		{Namespace: "fixed", Status: StatusFixed, FirstSeen: "run3", LastSeen: "run4", FixedAt: "run5", ConsecutiveFailures: 2, Error: "was", BuildID: 4},
+		{Namespace: "new", Status: StatusNew, FirstSeen: "run5", LastSeen: "run5", ConsecutiveFailures: 1, Error: "boom", BuildID: 5},
+		{Namespace: "old", Status: StatusFailing, FirstSeen: "run3", LastSeen: "run5", ConsecutiveFailures: 3, Error: "still", BuildID: 5},
+	}
+
+	if got := trends(runs, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("trends() = %+v, want %+v", got, want)
+	}
+
+	// nothing is new in the first run
+	first := trends(runs[4:], 2)
+	if len(first) != 1 || first[0].Status != StatusFailing {
+		t.Errorf("trends() of the first run = %+v, want the namespace failing", first)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package helmreleases
 
 import (
 	"bytes"
@@ -9,34 +9,18 @@ import (
 	"log"
 	"os"
 	"os/exec"
-	"strings"
 	"time"
 
 	client "github.com/ministryofjustice/cloud-platform-cli/pkg/client"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
 
 	"k8s.io/client-go/kubernetes"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	cluster "github.com/ministryofjustice/cloud-platform-cli/pkg/cluster"
-	utils "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
 )
 
-var (
-	region      = flag.String("region", os.Getenv("AWS_REGION"), "AWS Region")
-	kubeCfgPath = flag.String("kubeCfgPath", os.Getenv("KUBECONFIG"), "Path of the kube config file")
-
-	hoodawBucket   = flag.String("hoodaw-bucket", os.Getenv("HOODAW_BUCKET"), "AWS S3 bucket for hoodaw json reports")
-	bucket         = flag.String("bucket", os.Getenv("KUBECONFIG_S3_BUCKET"), "AWS S3 bucket for kubeconfig")
-	ctx            = flag.String("context", "live.cloud-platform.service.justice.gov.uk", "Kubernetes context specified in kubeconfig")
-	kubeconfig     = flag.String("kubeconfig", "kubeconfig", "Name of kubeconfig file in S3 bucket")
-	write_role_arn = flag.String("write-role-arn", os.Getenv("AWS_ROLE_ARN"), "AWS Role ARN to assume for writing to S3 bucket")
-)
-
-type helmNamespace struct {
-	Namespace string // key of JSON object "namespace" from helm list
-}
-
 type helmRelease struct {
 	Name             string `json:"name"`              // key of JSON object "name" from helm whatup
 	Namespace        string `json:"namespace"`         // key of JSON object "namespace" from helm whatup
@@ -47,58 +31,63 @@ type helmRelease struct {
 
 type resourceMap map[string]interface{}
 
-func main() {
-	contexts := []string{"live", "manager"}
+// Report compares the running and latest versions of the helm charts installed in each cluster.
+type Report struct {
+	contexts    utils.Contexts
+	kubeCfgPath string
+	region      string
+}
+
+// New returns the helm-releases report
+func New() *Report {
+	return &Report{}
+}
 
+func (r *Report) Name() string { return "helm-releases" }
+
+func (r *Report) Key() string { return "helm_releases.json" }
+
+func (r *Report) AddFlags(fs *flag.FlagSet) {
+	r.contexts = utils.Contexts{"live", "manager"}
+	fs.Var(&r.contexts, "context", "Comma separated list of the clusters to report on")
+	fs.StringVar(&r.region, "region", os.Getenv("AWS_REGION"), "AWS Region")
+	fs.StringVar(&r.kubeCfgPath, "kubeCfgPath", os.Getenv("KUBECONFIG"), "Path of the kube config file")
+}
+
+func (r *Report) Generate() ([]byte, error) {
 	var clusters []resourceMap
 	// Output the results of `helm whatup` as JSON, for each production cluster
-	for _, ctx := range contexts {
+	for _, ctx := range r.contexts {
 
-		creds, err := getCredentials(*region)
+		creds, err := getCredentials(r.region)
 		if err != nil {
-			log.Fatalln("failed to get aws creds: %w", err)
+			return nil, fmt.Errorf("failed to get aws creds: %w", err)
 		}
 
-		clientset, err := cluster.AuthToCluster(ctx, creds.Eks, *kubeCfgPath, creds.Profile)
+		clientset, err := cluster.AuthToCluster(ctx, creds.Eks, r.kubeCfgPath, creds.Profile)
 		if err != nil {
-			log.Fatalln("failed to auth to cluster: %w", err)
+			return nil, fmt.Errorf("failed to auth to cluster: %w", err)
 		}
 
 		namespaces, err := getCPNamespaces(clientset)
 		if err != nil {
-			log.Fatalln("error in getting namespaces", err)
+			return nil, fmt.Errorf("error in getting namespaces: %w", err)
 		}
 
 		releases, err := getHelmReleasesInNamespaces(namespaces)
 		if err != nil {
-			log.Fatalln("error in executing helm whatup", err)
+			return nil, fmt.Errorf("error in executing helm whatup: %w", err)
 		}
 
 		cluster := resourceMap{
-			"name": strings.Split(ctx, ".")[0],
+			"name": utils.ClusterName(ctx),
 			"apps": releases,
 		}
 
-		fmt.Println(cluster)
 		clusters = append(clusters, cluster)
 	}
 
-	jsonToPost, err := BuildJsonMap(clusters)
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
-
-	client, err := utils.S3Client("eu-west-2")
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
-
-	s3Err := utils.ExportToS3(client, *hoodawBucket, "helm_releases.json", jsonToPost)
-	if s3Err != nil {
-		log.Fatalln(s3Err.Error())
-	}
-
-	log.Println("successfully pushed json to bucket...", string(jsonToPost))
+	return BuildJsonMap(clusters)
 }
 
 func getCredentials(awsRegion string) (*client.AwsCredentials, error) {
@@ -134,7 +123,7 @@ func getHelmReleasesInNamespaces(namespaces []string) ([]helmRelease, error) {
 	for _, ns := range namespaces {
 		release, err := helmReleasesInNamespace(ns)
 		if err != nil {
-			log.Fatalln(err.Error())
+			return nil, err
 		}
 		releases = append(releases, release...)
 	}
@@ -165,19 +154,27 @@ func helmReleasesInNamespace(namespace string) ([]helmRelease, error) {
 	cmd.Stderr = &stderr
 	err := cmd.Run()
 	if err != nil {
-		fmt.Println(fmt.Sprint(err) + ": " + stderr.String())
+		return nil, fmt.Errorf("%s: %s", err, stderr.String())
+	}
+
+	releases, err := parseHelmReleases(out.Bytes())
+	if err != nil {
 		return nil, err
 	}
 
+	log.Printf("Executing helm whatup in namespace %s and got the following releases: %v\n", namespace, releases)
+
+	return releases, nil
+}
+
+// parseHelmReleases takes the json output of helm whatup and returns the releases
+func parseHelmReleases(helmWhatupJson []byte) ([]helmRelease, error) {
 	var rel map[string][]helmRelease
 
-	if err := json.Unmarshal(out.Bytes(), &rel); err != nil {
-		fmt.Println("Unable to unmarshal JSON: ", err)
-		return nil, err
+	if err := json.Unmarshal(helmWhatupJson, &rel); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal JSON: %w", err)
 	}
 
-	fmt.Println("Executing helm whatup in namespace %s", namespace, "and got the following releases: ", rel["releases"])
-
 	return rel["releases"], nil
 }
 
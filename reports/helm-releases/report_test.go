@@ -0,0 +1,55 @@
+package helmreleases
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseHelmReleases(t *testing.T) {
+	type args struct {
+		helmWhatupJson string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []helmRelease
+		wantErr bool
+	}{
+		{
+			name: "helm releases in namespace",
+			args: args{
+				helmWhatupJson: "{\"releases\":[{\"name\":\"rel1\",\"namespace\":\"ns1\",\"installed_version\":\"1.0.0\",\"latest_version\":\"1.1.0\",\"chart\":\"chart1\"}]}",
+			},
+			want: []helmRelease{
+				{
+					Name:             "rel1",
+					Namespace:        "ns1",
+					InstalledVersion: "1.0.0",
+					LatestVersion:    "1.1.0",
+					Chart:            "chart1",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid json",
+			args: args{
+				helmWhatupJson: "[{\"namespace\":\"ns1\"}]",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHelmReleases([]byte(tt.args.helmWhatupJson))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseHelmReleases() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseHelmReleases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
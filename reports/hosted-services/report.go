@@ -0,0 +1,228 @@
+package hostedservices
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/ministryofjustice/cloud-platform-environments/pkg/ingress"
+	"github.com/ministryofjustice/cloud-platform-environments/pkg/namespace"
+	utils "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// resourceMap is used to store both string:string and string:map[string]string key
+// value pairs. The HOODAW API requires the first entry of map to contain a string:string,
+// the rest of the map consists of a primary key (string) with a value containing a map (string:string)
+// of Kubernetes namespaces and hostnames that don't contain the annotation contained within
+// the variable 'annotation'.
+type resourceMap map[string]interface{}
+
+// HostedService is a namespace with the hosts of all of its ingresses. DomainNames holds
+// the distinct hostnames for the pages which only list those.
+type HostedService struct {
+	namespace.Namespace
+	Ingresses []IngressHost
+}
+
+// IngressHost is a hostname from the rules or TLS hosts of an ingress
+type IngressHost struct {
+	Hostname     string
+	Ingress      string
+	IngressClass string
+	TLS          bool
+}
+
+// Report lists the namespaces in each cluster with their annotations and domain names.
+type Report struct {
+	bucket      string
+	contexts    utils.Contexts
+	kubeconfig  string
+	kubeCfgPath string
+	region      string
+}
+
+// New returns the hosted-services report
+func New() *Report {
+	return &Report{}
+}
+
+func (r *Report) Name() string { return "hosted-services" }
+
+func (r *Report) Key() string { return "hosted_services.json" }
+
+func (r *Report) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&r.bucket, "bucket", os.Getenv("KUBECONFIG_S3_BUCKET"), "AWS S3 bucket for kubeconfig")
+	r.contexts = utils.Contexts{"live.cloud-platform.service.justice.gov.uk"}
+	fs.Var(&r.contexts, "context", "Comma separated list of Kubernetes contexts specified in kubeconfig")
+	fs.StringVar(&r.kubeconfig, "kubeconfig", "kubeconfig", "Name of kubeconfig file in S3 bucket")
+	fs.StringVar(&r.kubeCfgPath, "kubeCfgPath", os.Getenv("KUBECONFIG"), "Path of the kube config file, used when no bucket is set")
+	fs.StringVar(&r.region, "region", os.Getenv("AWS_REGION"), "AWS Region")
+}
+
+func (r *Report) Generate() ([]byte, error) {
+	// make namespace map, keyed on cluster/namespace as a namespace name can be used in more than one cluster
+	nsDetailsMap := make(map[string]HostedService, 0)
+
+	for _, ctx := range r.contexts {
+		cluster := utils.ClusterName(ctx)
+
+		// Gain access to a Kubernetes cluster using a config file stored in an S3 bucket.
+		clientset, err := utils.KubeClient(r.bucket, r.kubeconfig, r.region, r.kubeCfgPath, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to authenticate to the %s cluster: %w", cluster, err)
+		}
+
+		// Get the list of namespaces from the cluster which is set in the clientset
+		namespaces, err := namespace.GetAllNamespacesFromCluster(clientset)
+		if err != nil {
+			return nil, err
+		}
+
+		// Get all ingress resources
+		ingressList, err := ingress.GetAllIngressesFromCluster(clientset)
+		if err != nil {
+			return nil, err
+		}
+
+		// Build the ingresses Map
+		ingressesMap := BuildIngressesMap(ingressList.Items)
+
+		// get required details of each namespace with its ingresses and store it in namespace map
+		for _, ns := range namespaces {
+			namespaceDetails := GetNamespaceDetails(ns)
+			namespaceDetails.Cluster = cluster
+
+			hosts := ingressesMap[ns.Name]
+			if hosts == nil {
+				hosts = []IngressHost{}
+			}
+			namespaceDetails.DomainNames = domainNames(hosts)
+
+			nsDetailsMap[cluster+"/"+namespaceDetails.Name] = HostedService{
+				Namespace: namespaceDetails,
+				Ingresses: hosts,
+			}
+		}
+	}
+
+	return BuildJsonMap(nsDetailsMap)
+}
+
+// GetNamespaceDetails takes a Namespace of type v1.namespace and stores the required annotations
+// and labels into a struct and return the result
+func GetNamespaceDetails(ns v1.Namespace) namespace.Namespace {
+	return namespace.Namespace{
+		Name:             ns.Name,
+		Application:      ns.Annotations["cloud-platform.justice.gov.uk/application"],
+		BusinessUnit:     ns.Annotations["cloud-platform.justice.gov.uk/business-unit"],
+		DeploymentType:   ns.Labels["cloud-platform.justice.gov.uk/environment-name"],
+		GithubURL:        ns.Annotations["cloud-platform.justice.gov.uk/source-code"],
+		TeamName:         ns.Annotations["cloud-platform.justice.gov.uk/team-name"],
+		TeamSlackChannel: ns.Annotations["cloud-platform.justice.gov.uk/slack-channel"],
+		DomainNames:      []string{},
+	}
+}
+
+// BuildIngressesMap takes the Ingress list and return a map with key as namespace and value
+// with every host of the ingresses in the namespace, from both the rules and the TLS blocks
+func BuildIngressesMap(ingressItems []networkingv1.Ingress) map[string][]IngressHost {
+	ingressMap := make(map[string][]IngressHost, 0)
+
+	for _, i := range ingressItems {
+		tlsHosts := make(map[string]bool)
+		for _, tls := range i.Spec.TLS {
+			for _, host := range tls.Hosts {
+				tlsHosts[host] = true
+			}
+		}
+
+		hosts := make([]string, 0)
+		for _, rule := range i.Spec.Rules {
+			hosts = append(hosts, rule.Host)
+		}
+		for _, tls := range i.Spec.TLS {
+			hosts = append(hosts, tls.Hosts...)
+		}
+
+		seen := make(map[string]bool)
+		for _, host := range hosts {
+			if host == "" || seen[host] {
+				continue
+			}
+			seen[host] = true
+
+			ingressMap[i.Namespace] = append(ingressMap[i.Namespace], IngressHost{
+				Hostname:     host,
+				Ingress:      i.Name,
+				IngressClass: ingressClass(i),
+				TLS:          tlsHosts[host],
+			})
+		}
+	}
+	return ingressMap
+}
+
+// ingressClass returns the class of the ingress from its spec, or the annotation
+// used before ingressClassName was added
+func ingressClass(i networkingv1.Ingress) string {
+	if i.Spec.IngressClassName != nil {
+		return *i.Spec.IngressClassName
+	}
+
+	return i.Annotations["kubernetes.io/ingress.class"]
+}
+
+// domainNames returns the distinct hostnames of the ingress hosts
+func domainNames(hosts []IngressHost) []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, h := range hosts {
+		if !seen[h.Hostname] {
+			seen[h.Hostname] = true
+			names = append(names, h.Hostname)
+		}
+	}
+
+	return names
+}
+
+// BuildJsonMap takes a map with namespace key and hosted service struct as value, sort the map, flatten to a
+// slice and return a json encoded map
+func BuildJsonMap(hostedservices map[string]HostedService) ([]byte, error) {
+	// To handle generics in the data type, we need to create a new map,
+	// add the first key string:string and then the second key/value string:map[string]string.
+	// As per the requirements of the HOODAW API.
+
+	// sort the keys by ascending order
+	keys := make([]string, 0, len(hostedservices))
+	for key := range hostedservices {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// flatten the map to a slice which is expected by the HOODAW API
+	flattenMap := make([]HostedService, 0)
+
+	for _, k := range keys {
+		flattenMap = append(flattenMap, hostedservices[k])
+	}
+
+	jsonMap := resourceMap{
+		"updated_at":        time.Now().Format("2006-01-2 15:4:5 UTC"),
+		"namespace_details": flattenMap,
+	}
+
+	jsonStr, err := json.Marshal(jsonMap)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonStr, nil
+}
@@ -0,0 +1,85 @@
+package hostedservices
+
+import (
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBuildIngressesMap(t *testing.T) {
+	nginx := "default"
+
+	tests := []struct {
+		name         string
+		ingressItems []networkingv1.Ingress
+		want         map[string][]IngressHost
+	}{
+		{
+			name: "rule and tls hosts",
+			ingressItems: []networkingv1.Ingress{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "app"},
+					Spec: networkingv1.IngressSpec{
+						IngressClassName: &nginx,
+						Rules: []networkingv1.IngressRule{
+							{Host: "app.service.justice.gov.uk"},
+							{Host: "app-internal.service.justice.gov.uk"},
+						},
+						TLS: []networkingv1.IngressTLS{
+							{Hosts: []string{"app.service.justice.gov.uk", "www.app.service.justice.gov.uk"}},
+						},
+					},
+				},
+			},
+			want: map[string][]IngressHost{
+				"ns1": {
+					{Hostname: "app.service.justice.gov.uk", Ingress: "app", IngressClass: "default", TLS: true},
+					{Hostname: "app-internal.service.justice.gov.uk", Ingress: "app", IngressClass: "default", TLS: false},
+					{Hostname: "www.app.service.justice.gov.uk", Ingress: "app", IngressClass: "default", TLS: true},
+				},
+			},
+		},
+		{
+			name: "non tls ingress with class annotation",
+			ingressItems: []networkingv1.Ingress{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace:   "ns2",
+						Name:        "legacy",
+						Annotations: map[string]string{"kubernetes.io/ingress.class": "modsec"},
+					},
+					Spec: networkingv1.IngressSpec{
+						Rules: []networkingv1.IngressRule{{Host: "legacy.service.justice.gov.uk"}, {}},
+					},
+				},
+			},
+			want: map[string][]IngressHost{
+				"ns2": {
+					{Hostname: "legacy.service.justice.gov.uk", Ingress: "legacy", IngressClass: "modsec", TLS: false},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildIngressesMap(tt.ingressItems); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildIngressesMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_domainNames(t *testing.T) {
+	hosts := []IngressHost{
+		{Hostname: "a.service.justice.gov.uk", Ingress: "one"},
+		{Hostname: "b.service.justice.gov.uk", Ingress: "one"},
+		{Hostname: "a.service.justice.gov.uk", Ingress: "two"},
+	}
+	want := []string{"a.service.justice.gov.uk", "b.service.justice.gov.uk"}
+
+	if got := domainNames(hosts); !reflect.DeepEqual(got, want) {
+		t.Errorf("domainNames() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,252 @@
+package infrastructuredeployments
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Deployment is a merged PR which changed the paths of its repository
+type Deployment struct {
+	Repository    string
+	Number        int
+	Title         string
+	URL           string
+	FirstCommitAt time.Time
+	MergedAt      time.Time
+	Labels        []string
+}
+
+// Metrics are the DORA delivery metrics of the deployments over the period. The lead time is from
+// the first commit of a PR to its merge and the time to restore from the merge of a failed
+// deployment to the merge of the deployment which restored it, both as medians in hours.
+type Metrics struct {
+	PeriodStart        string  `json:"period_start"`
+	PeriodEnd          string  `json:"period_end"`
+	Deployments        int     `json:"deployments"`
+	Failures           int     `json:"failures"`
+	DeploymentsPerWeek float64 `json:"deployments_per_week"`
+	LeadTimeHours      float64 `json:"lead_time_hours"`
+	ChangeFailureRate  float64 `json:"change_failure_rate"`
+	TimeToRestoreHours float64 `json:"time_to_restore_hours"`
+}
+
+// Week is the deployments merged in the week starting on Monday Start
+type Week struct {
+	Start         string  `json:"start"`
+	Deployments   int     `json:"deployments"`
+	Failures      int     `json:"failures"`
+	LeadTimeHours float64 `json:"lead_time_hours"`
+}
+
+// Failure is a deployment which was reverted or labelled as causing an incident. RestoredBy is the
+// deployment which restored the service, the revert or else the next deployment to the repository.
+// A revert of a deployment from before the period is a failure of the revert itself.
+type Failure struct {
+	Repository   string  `json:"repository"`
+	Number       int     `json:"number"`
+	Title        string  `json:"title"`
+	URL          string  `json:"url"`
+	MergedAt     string  `json:"merged_at"`
+	Reason       string  `json:"reason"`
+	RestoredBy   string  `json:"restored_by"`
+	RestoreHours float64 `json:"restore_hours"`
+}
+
+// The reason a deployment is a failure
+const (
+	FailureReverted = "reverted"
+	FailureIncident = "incident"
+	FailureRevert   = "revert"
+)
+
+// revertTitle is the title GitHub gives the PR reverting another PR
+var revertTitle = regexp.MustCompile(`^Revert "(.*)"$`)
+
+// isRevert is the assumption the report has always made, a PR with revert in its title reverts a failed deployment
+func isRevert(d Deployment) bool {
+	return strings.Contains(strings.ToLower(d.Title), "revert")
+}
+
+// doraMetrics works out the metrics of the deployments merged between start and end, with their
+// weekly figures and the failures. A deployment is a failure when a later revert names it, or it
+// has one of the incident labels.
+func doraMetrics(deployments []Deployment, start, end time.Time, incidentLabels []string) (Metrics, []Week, []Failure) {
+	sorted := make([]Deployment, len(deployments))
+	copy(sorted, deployments)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].MergedAt.Before(sorted[j].MergedAt) })
+
+	incident := make(map[string]bool)
+	for _, l := range incidentLabels {
+		incident[strings.ToLower(l)] = true
+	}
+
+	failures := make([]Failure, 0)
+	failed := make(map[int]bool)
+	restoreHours := make([]float64, 0)
+
+	addFailure := func(i int, reason string, restoredBy *Deployment) {
+		d := sorted[i]
+		f := Failure{
+			Repository: d.Repository,
+			Number:     d.Number,
+			Title:      d.Title,
+			URL:        d.URL,
+			MergedAt:   d.MergedAt.Format(time.RFC3339),
+			Reason:     reason,
+		}
+		if restoredBy != nil {
+			f.RestoredBy = restoredBy.URL
+			f.RestoreHours = hours(restoredBy.MergedAt.Sub(d.MergedAt))
+			restoreHours = append(restoreHours, f.RestoreHours)
+		}
+		failed[i] = true
+		failures = append(failures, f)
+	}
+
+	for i, d := range sorted {
+		if !isRevert(d) {
+			continue
+		}
+
+		// the latest deployment to the repository before the revert with the reverted title
+		original := -1
+		if m := revertTitle.FindStringSubmatch(d.Title); m != nil {
+			for j := i - 1; j >= 0; j-- {
+				if sorted[j].Repository == d.Repository && sorted[j].Title == m[1] && !failed[j] {
+					original = j
+					break
+				}
+			}
+		}
+
+		revert := d
+		if original >= 0 {
+			addFailure(original, FailureReverted, &revert)
+		} else {
+			addFailure(i, FailureRevert, nil)
+		}
+	}
+
+	for i, d := range sorted {
+		if failed[i] || !hasLabel(d, incident) {
+			continue
+		}
+
+		var next *Deployment
+		for j := i + 1; j < len(sorted); j++ {
+			if sorted[j].Repository == d.Repository {
+				next = &sorted[j]
+				break
+			}
+		}
+		addFailure(i, FailureIncident, next)
+	}
+
+	sort.SliceStable(failures, func(i, j int) bool { return failures[i].MergedAt > failures[j].MergedAt })
+
+	weeks, leadTimes := weeklyDeployments(sorted, failed, start, end)
+
+	m := Metrics{
+		PeriodStart:        start.Format("2006-01-02"),
+		PeriodEnd:          end.Format("2006-01-02"),
+		Deployments:        len(sorted),
+		Failures:           len(failures),
+		LeadTimeHours:      median(leadTimes),
+		TimeToRestoreHours: median(restoreHours),
+	}
+	if len(weeks) > 0 {
+		m.DeploymentsPerWeek = round(float64(len(sorted)) / float64(len(weeks)))
+	}
+	if len(sorted) > 0 {
+		m.ChangeFailureRate = round(float64(len(failures)) / float64(len(sorted)))
+	}
+
+	return m, weeks, failures
+}
+
+// weeklyDeployments returns every week from start to end, including those without deployments,
+// and the lead time of each deployment
+func weeklyDeployments(sorted []Deployment, failed map[int]bool, start, end time.Time) ([]Week, []float64) {
+	weeks := make([]Week, 0)
+	index := make(map[string]int)
+	for w := weekStart(start); !w.After(end); w = w.AddDate(0, 0, 7) {
+		index[w.Format("2006-01-02")] = len(weeks)
+		weeks = append(weeks, Week{Start: w.Format("2006-01-02")})
+	}
+
+	leadTimes := make([]float64, 0)
+	weekLeadTimes := make([][]float64, len(weeks))
+
+	for i, d := range sorted {
+		leadTime := 0.0
+		if !d.FirstCommitAt.IsZero() {
+			leadTime = hours(d.MergedAt.Sub(d.FirstCommitAt))
+			leadTimes = append(leadTimes, leadTime)
+		}
+
+		w, ok := index[weekStart(d.MergedAt).Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		weeks[w].Deployments++
+		if failed[i] {
+			weeks[w].Failures++
+		}
+		if !d.FirstCommitAt.IsZero() {
+			weekLeadTimes[w] = append(weekLeadTimes[w], leadTime)
+		}
+	}
+
+	for i := range weeks {
+		weeks[i].LeadTimeHours = median(weekLeadTimes[i])
+	}
+
+	return weeks, leadTimes
+}
+
+// weekStart returns the Monday of the week of t, in UTC
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	days := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-days, 0, 0, 0, 0, time.UTC)
+}
+
+func hasLabel(d Deployment, labels map[string]bool) bool {
+	for _, l := range d.Labels {
+		if labels[strings.ToLower(l)] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hours(d time.Duration) float64 {
+	return round(d.Hours())
+}
+
+// median of the values, 0 when there are none
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return round((sorted[mid-1] + sorted[mid]) / 2)
+	}
+
+	return sorted[mid]
+}
+
+// round to 2 decimal places
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,183 @@
+package infrastructuredeployments
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/shurcooL/githubv4"
+	"golang.org/x/oauth2"
+)
+
+// defaultGitHubURL is the base URL of the GitHub API, the GraphQL API is at graphql under it
+const defaultGitHubURL = "https://api.github.com/"
+
+// prSearcher runs GraphQL queries, used to search for the merged PRs of a repository
+type prSearcher interface {
+	Query(ctx context.Context, q interface{}, variables map[string]interface{}) error
+}
+
+// fileLister lists the files changed by a PR with the REST API
+type fileLister interface {
+	ListFiles(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.CommitFile, *github.Response, error)
+}
+
+// newGitHubClients returns the GraphQL and REST clients of the GitHub API at baseURL, which
+// authenticate with the token and wait out rate limits
+func newGitHubClients(baseURL, token string, rateLimit *rateLimitTransport) (prSearcher, fileLister, error) {
+	if token == "" {
+		return nil, nil, errors.New("personal access token is empty, unable to create GitHub client")
+	}
+
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid GitHub URL %q: %w", baseURL, err)
+	}
+
+	httpClient := oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	rateLimit.next = httpClient.Transport
+	httpClient.Transport = rateLimit
+
+	rest := github.NewClient(httpClient)
+	rest.BaseURL = base
+
+	graphql := githubv4.NewEnterpriseClient(base.ResolveReference(&url.URL{Path: "graphql"}).String(), httpClient)
+
+	return graphql, rest.PullRequests, nil
+}
+
+// rateLimitTransport retries the requests GitHub rate limits, waiting for as long as GitHub says
+// to, or backing off exponentially from backoff when it doesn't say. It gives up after retries
+// attempts or when it would have to wait longer than maxWait, returning the rate limited response.
+type rateLimitTransport struct {
+	next    http.RoundTripper
+	retries int
+	backoff time.Duration
+	maxWait time.Duration
+	now     func() time.Time
+	wait    func(ctx context.Context, d time.Duration) error
+}
+
+// newRateLimitTransport returns a rateLimitTransport which sleeps while it waits
+func newRateLimitTransport(retries int, backoff, maxWait time.Duration) *rateLimitTransport {
+	return &rateLimitTransport{
+		retries: retries,
+		backoff: backoff,
+		maxWait: maxWait,
+		now:     time.Now,
+		wait:    sleep,
+	}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		limited, err := rateLimited(resp)
+		if err != nil {
+			return nil, err
+		}
+		if !limited || attempt >= t.retries {
+			return resp, nil
+		}
+
+		d := t.waitFor(resp, attempt)
+		if d > t.maxWait {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		log.Printf("GitHub rate limited %s %s, retrying in %s\n", req.Method, req.URL.Path, d)
+		if err := t.wait(req.Context(), d); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitFor returns how long to wait before retrying the rate limited response, from its
+// Retry-After or X-RateLimit-Reset header, or else the backoff of the attempt
+func (t *rateLimitTransport) waitFor(resp *http.Response, attempt int) time.Duration {
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			if d := time.Unix(reset, 0).Sub(t.now()); d > 0 {
+				// the reset is to the second, so allow for the clocks not matching
+				return d + time.Second
+			}
+			return 0
+		}
+	}
+
+	return t.backoff << attempt
+}
+
+// rateLimited reports whether GitHub refused the request for going over a rate limit. The REST
+// API returns a 403 or 429, and the GraphQL API a 200 with a RATE_LIMITED error, so the body of
+// a 200 with no requests remaining is read to check and put back.
+func rateLimited(resp *http.Response) (bool, error) {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true, nil
+	case http.StatusForbidden:
+		return resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0", nil
+	case http.StatusOK:
+		if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+			return false, nil
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return false, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		return bytes.Contains(body, []byte(`"RATE_LIMITED"`)), nil
+	}
+
+	return false, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
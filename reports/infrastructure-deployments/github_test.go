@@ -0,0 +1,325 @@
+package infrastructuredeployments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// response is a canned response of a stand-in GitHub server
+type response struct {
+	status  int
+	headers map[string]string
+	body    string
+}
+
+func (resp response) write(w http.ResponseWriter) {
+	for k, v := range resp.headers {
+		w.Header().Set(k, v)
+	}
+	if resp.status != 0 {
+		w.WriteHeader(resp.status)
+	}
+	io.WriteString(w, resp.body)
+}
+
+func Test_rateLimitTransport(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	reset := strconv.FormatInt(now.Add(30*time.Second).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		responses []response
+		maxWait   time.Duration
+		wantWaits []time.Duration
+		wantCode  int
+	}{
+		{
+			name:      "not rate limited",
+			responses: []response{{body: "ok"}},
+			wantCode:  http.StatusOK,
+		},
+		{
+			name: "waits for the reset then backs off",
+			responses: []response{
+				{status: http.StatusForbidden, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}},
+				{status: http.StatusTooManyRequests},
+				{body: "ok"},
+			},
+			wantWaits: []time.Duration{31 * time.Second, 2 * time.Minute},
+			wantCode:  http.StatusOK,
+		},
+		{
+			name: "retry after",
+			responses: []response{
+				{status: http.StatusForbidden, headers: map[string]string{"Retry-After": "5"}},
+				{body: "ok"},
+			},
+			wantWaits: []time.Duration{5 * time.Second},
+			wantCode:  http.StatusOK,
+		},
+		{
+			name: "graphql rate limited",
+			responses: []response{
+				{headers: map[string]string{"X-RateLimit-Remaining": "0"}, body: `{"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded"}]}`},
+				{body: "ok"},
+			},
+			wantWaits: []time.Duration{time.Minute},
+			wantCode:  http.StatusOK,
+		},
+		{
+			name:      "forbidden without a rate limit",
+			responses: []response{{status: http.StatusForbidden}},
+			wantCode:  http.StatusForbidden,
+		},
+		{
+			name: "gives up after the retries",
+			responses: []response{
+				{status: http.StatusTooManyRequests},
+				{status: http.StatusTooManyRequests},
+				{status: http.StatusTooManyRequests},
+			},
+			wantWaits: []time.Duration{time.Minute, 2 * time.Minute},
+			wantCode:  http.StatusTooManyRequests,
+		},
+		{
+			name:      "gives up when the wait is too long",
+			responses: []response{{status: http.StatusForbidden, headers: map[string]string{"Retry-After": "3600"}}},
+			maxWait:   time.Hour - time.Second,
+			wantCode:  http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if body, _ := io.ReadAll(r.Body); string(body) != "query" {
+					t.Errorf("request %d has body %q, want %q", requests, body, "query")
+				}
+				tt.responses[requests].write(w)
+				requests++
+			}))
+			defer server.Close()
+
+			maxWait := tt.maxWait
+			if maxWait == 0 {
+				maxWait = time.Hour
+			}
+
+			waits := make([]time.Duration, 0)
+			transport := newRateLimitTransport(2, time.Minute, maxWait)
+			transport.now = func() time.Time { return now }
+			transport.wait = func(_ context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+
+			client := &http.Client{Transport: transport}
+			resp, err := client.Post(server.URL, "application/json", strings.NewReader("query"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			if requests != len(tt.responses) {
+				t.Errorf("made %d requests, want %d", requests, len(tt.responses))
+			}
+			if len(tt.wantWaits) == 0 {
+				tt.wantWaits = []time.Duration{}
+			}
+			if !reflect.DeepEqual(waits, tt.wantWaits) {
+				t.Errorf("waited %v, want %v", waits, tt.wantWaits)
+			}
+		})
+	}
+}
+
+// stubGitHub stands in for the GitHub API. The search of a repository returns its PRs a page at a
+// time, and the files of a PR are listed from files, an error for a PR with no files. A repository
+// without PRs can't be searched.
+type stubGitHub struct {
+	prs   map[string][][]int
+	files map[int][]string
+
+	mu       sync.Mutex
+	searches int
+}
+
+func (s *stubGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/graphql" {
+		s.search(w, r)
+		return
+	}
+
+	var owner, repo string
+	var number int
+	if _, err := fmt.Sscanf(strings.ReplaceAll(r.URL.Path, "/", " "), " repos %s %s pulls %d files", &owner, &repo, &number); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	files, ok := s.files[number]
+	if !ok {
+		http.Error(w, `{"message": "Server Error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	commitFiles := make([]map[string]string, 0)
+	for _, f := range files {
+		commitFiles = append(commitFiles, map[string]string{"filename": f})
+	}
+	json.NewEncoder(w).Encode(commitFiles)
+}
+
+func (s *stubGitHub) search(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.searches++
+	s.mu.Unlock()
+
+	var body struct {
+		Variables struct {
+			SearchQuery string  `json:"searchQuery"`
+			Cursor      *string `json:"cursor"`
+		} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo := strings.TrimPrefix(strings.Fields(body.Variables.SearchQuery)[0], "repo:")
+	pages, ok := s.prs[repo]
+	if !ok {
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+
+	page := 0
+	if body.Variables.Cursor != nil {
+		page, _ = strconv.Atoi(*body.Variables.Cursor)
+	}
+
+	nodes := make([]map[string]interface{}, 0)
+	for _, number := range pages[page] {
+		nodes = append(nodes, map[string]interface{}{
+			"title":    fmt.Sprintf("PR %d", number),
+			"url":      fmt.Sprintf("https://github.com/%s/pull/%d", repo, number),
+			"number":   number,
+			"mergedAt": "2024-01-01T10:00:00Z",
+			"labels":   map[string]interface{}{"nodes": []interface{}{}},
+			"commits": map[string]interface{}{"nodes": []interface{}{
+				map[string]interface{}{"commit": map[string]string{"authoredDate": "2024-01-01T08:00:00Z"}},
+			}},
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{
+			"search": map[string]interface{}{
+				"pageInfo": map[string]interface{}{"endCursor": strconv.Itoa(page + 1), "hasNextPage": page+1 < len(pages)},
+				"nodes":    nodes,
+			},
+		},
+	})
+}
+
+func TestReport_Generate(t *testing.T) {
+	tests := []struct {
+		name         string
+		repositories []string
+		wantDeployed string
+		wantErrors   int
+		wantErr      bool
+	}{
+		{
+			name:         "pages through the search",
+			repositories: []string{"infra:terraform/**"},
+			wantDeployed: "2",
+			wantErrors:   numMonths,
+		},
+		{
+			name:         "reports the repositories which can't be searched",
+			repositories: []string{"infra:terraform/**", "missing"},
+			wantDeployed: "2",
+			wantErrors:   2 * numMonths,
+		},
+		{
+			name:         "fails when every search fails",
+			repositories: []string{"missing"},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubGitHub{
+				prs: map[string][][]int{"moj/infra": {{1, 2}, {3, 4}}},
+				files: map[int][]string{
+					1: {"terraform/main.tf"},
+					2: {"README.md"},
+					3: {"README.md", "terraform/modules/vpc.tf"},
+				},
+			}
+			server := httptest.NewServer(stub)
+			defer server.Close()
+
+			r := &Report{org: "moj"}
+			for _, repo := range tt.repositories {
+				if err := r.repositories.Set(repo); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			transport := newRateLimitTransport(0, time.Millisecond, time.Second)
+			search, files, err := newGitHubClients(server.URL, "token", transport)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.search, r.files = search, files
+
+			data, err := r.Generate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var got struct {
+				Deployments []map[string]string `json:"deployments"`
+				Errors      []string            `json:"errors"`
+			}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got.Deployments) != numMonths {
+				t.Fatalf("got %d months, want %d", len(got.Deployments), numMonths)
+			}
+			for _, month := range got.Deployments {
+				if month["deployed"] != tt.wantDeployed {
+					t.Errorf("%s deployed = %s, want %s", month["date"], month["deployed"], tt.wantDeployed)
+				}
+			}
+			// PR 4 has no files so every month has an error listing them
+			if len(got.Errors) != tt.wantErrors {
+				t.Errorf("got %d errors, want %d: %v", len(got.Errors), tt.wantErrors, got.Errors)
+			}
+			// two pages of infra and one failed search of any other repository each month
+			if want := numMonths * (len(tt.repositories) + 1); stub.searches != want {
+				t.Errorf("made %d searches, want %d", stub.searches, want)
+			}
+		})
+	}
+}
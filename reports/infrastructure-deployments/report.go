@@ -0,0 +1,416 @@
+package infrastructuredeployments
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/shurcooL/githubv4"
+)
+
+// resourceMap is used to store both string:string and string:map[string]string key
+// value pairs. The HOODAW API requires the first entry of map to contain a string:string,
+// the rest of the map consists of a primary key (string) with a value containing a map (string:string)
+// of Kubernetes namespaces and hostnames that don't contain the annotation contained within
+// the variable 'annotation'.
+type resourceMap map[string]interface{}
+
+const (
+	// defaultRepository is the repository and path globs reported on when --repository isn't given
+	defaultRepository = "cloud-platform-infrastructure:terraform/aws-accounts/cloud-platform-aws/**"
+
+	// Number of months to generate report
+	numMonths = 12
+	// number of PRs to fetch per page of the search, the most GitHub allows
+	prCount = 100
+	// rateLimitBackoff is the first wait after a rate limit which doesn't say how long to wait for,
+	// GitHub asks for at least a minute
+	rateLimitBackoff = time.Minute
+)
+
+type nodes struct {
+	PullRequest struct {
+		Title    githubv4.String
+		Url      githubv4.String
+		Number   githubv4.Int
+		MergedAt githubv4.DateTime
+		Labels   struct {
+			Nodes []struct {
+				Name githubv4.String
+			}
+		} `graphql:"labels(first: 20)"`
+		// the commits of a PR are oldest first
+		Commits struct {
+			Nodes []struct {
+				Commit struct {
+					AuthoredDate githubv4.DateTime
+				}
+			}
+		} `graphql:"commits(first: 1)"`
+	} `graphql:"... on PullRequest"`
+}
+
+// repository is a GitHub repository and the globs of the paths whose changes are deployments.
+// Owner is empty when it's the --org.
+type repository struct {
+	Owner string
+	Name  string
+	Paths []*regexp.Regexp
+	flag  string
+}
+
+// repositories is the repeatable --repository flag, each given as [owner/]name[:glob,glob...]
+// where ** in a glob matches any number of directories. The first one replaces the default.
+type repositories struct {
+	repos []repository
+	set   bool
+}
+
+func (r *repositories) String() string {
+	if r == nil {
+		return ""
+	}
+
+	s := make([]string, 0, len(r.repos))
+	for _, repo := range r.repos {
+		s = append(s, repo.flag)
+	}
+
+	return strings.Join(s, " ")
+}
+
+func (r *repositories) Set(value string) error {
+	repo, err := parseRepository(value)
+	if err != nil {
+		return err
+	}
+
+	// the first repository given replaces the default
+	if !r.set {
+		r.repos = nil
+		r.set = true
+	}
+	r.repos = append(r.repos, repo)
+
+	return nil
+}
+
+// parseRepository parses a --repository flag, without any globs every change is a deployment
+func parseRepository(value string) (repository, error) {
+	name, globs, _ := strings.Cut(value, ":")
+
+	repo := repository{Name: name, flag: value}
+	if owner, n, ok := strings.Cut(name, "/"); ok {
+		repo.Owner, repo.Name = owner, n
+	}
+	if repo.Name == "" {
+		return repo, fmt.Errorf("no repository name in %q", value)
+	}
+
+	for _, glob := range strings.Split(globs, ",") {
+		if glob = strings.TrimSpace(glob); glob != "" {
+			repo.Paths = append(repo.Paths, globRegexp(glob))
+		}
+	}
+	if len(repo.Paths) == 0 {
+		repo.Paths = append(repo.Paths, globRegexp("**"))
+	}
+
+	return repo, nil
+}
+
+// globRegexp returns the regexp of a path glob, where * and ? match within a directory and **
+// matches any number of directories. A glob without wildcards matches the path and everything in it.
+func globRegexp(glob string) *regexp.Regexp {
+	if !strings.ContainsAny(glob, "*?") {
+		glob = strings.TrimSuffix(glob, "/") + "/**"
+	}
+
+	var re strings.Builder
+	re.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			re.WriteString("[^/]*")
+		case glob[i] == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	re.WriteString("$")
+
+	return regexp.MustCompile(re.String())
+}
+
+// matches reports whether a changed file is under one of the paths of the repository
+func (repo repository) matches(filename string) bool {
+	for _, path := range repo.Paths {
+		if path.MatchString(filename) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type date struct {
+	first      time.Time
+	last       time.Time
+	monthIndex string
+}
+
+type infraPRs struct {
+	deployed, failed int
+}
+
+// Report counts the deployed and failed infrastructure PRs for each of the last numMonths months,
+// across all of the repositories, with the DORA metrics of the deployments over the period. The
+// searches and PRs which fail are reported as errors rather than failing the report, unless every
+// search fails. search and files are created from the flags when they aren't set.
+type Report struct {
+	githubURL      string
+	incidentLabels string
+	maxWait        time.Duration
+	org            string
+	repositories   repositories
+	retries        int
+	token          string
+
+	search prSearcher
+	files  fileLister
+}
+
+// New returns the infrastructure-deployments report
+func New() *Report {
+	return &Report{}
+}
+
+func (r *Report) Name() string { return "infrastructure-deployments" }
+
+func (r *Report) Key() string { return "infrastructure_deployments.json" }
+
+func (r *Report) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&r.githubURL, "github-url", defaultGitHubURL, "Base URL of the GitHub API, the GraphQL API is at graphql under it")
+	fs.StringVar(&r.incidentLabels, "incident-labels", "incident", "Comma separated labels of the PRs which caused an incident")
+	fs.StringVar(&r.org, "org", "ministryofjustice", "GitHub user or organisation.")
+	fs.IntVar(&r.retries, "rate-limit-retries", 5, "Number of times to retry a request GitHub rate limits")
+	fs.DurationVar(&r.maxWait, "rate-limit-wait", 15*time.Minute, "Longest to wait for a GitHub rate limit to reset before giving up on the request")
+	repo, _ := parseRepository(defaultRepository)
+	r.repositories = repositories{repos: []repository{repo}}
+	fs.Var(&r.repositories, "repository", "Repository to check the PRs of as [owner/]name[:glob,glob...], where the globs are the paths of the deployments and ** matches any directories, can be repeated")
+	fs.StringVar(&r.token, "token", os.Getenv("GITHUB_OAUTH_TOKEN"), "Personal access token for GitHub API.")
+}
+
+func (r *Report) Generate() ([]byte, error) {
+	if r.search == nil || r.files == nil {
+		search, files, err := newGitHubClients(r.githubURL, r.token, newRateLimitTransport(r.retries, rateLimitBackoff, r.maxWait))
+		if err != nil {
+			return nil, err
+		}
+		r.search, r.files = search, files
+	}
+
+	infraReport := make([]map[string]string, 0)
+	deployments := make([]Deployment, 0)
+	errs := make([]string, 0)
+	searched := 0
+
+	// Start from m = 0 which is the current month.
+	// This report generated data for past 12 months based on current month
+	for m := 0; m < numMonths; m++ {
+		infraPRMap := make(map[string]string)
+
+		date := getFirstLastDayofMonth(m)
+
+		total := new(infraPRs)
+		for _, repo := range r.repositories.repos {
+			if repo.Owner == "" {
+				repo.Owner = r.org
+			}
+
+			nodes, err := r.getPrsPerMonth(repo, date, prCount)
+			if err != nil {
+				e := fmt.Sprintf("unable to search %s/%s for %s: %s", repo.Owner, repo.Name, date.monthIndex, err)
+				log.Println(e)
+				errs = append(errs, e)
+				continue
+			}
+			searched++
+
+			// query PRs that have changes under the paths of the repository
+			deployed, failed := r.getDeployments(repo, nodes)
+			errs = append(errs, failed...)
+			for _, d := range deployed {
+				if isRevert(d) {
+					total.failed++
+				} else {
+					total.deployed++
+				}
+			}
+			deployments = append(deployments, deployed...)
+		}
+
+		infraPRMap["date"] = date.monthIndex
+		infraPRMap["deployed"] = strconv.Itoa(total.deployed)
+		infraPRMap["failed"] = strconv.Itoa(total.failed)
+		infraReport = append(infraReport, infraPRMap)
+	}
+
+	// without a single search the counts are all missing rather than zero
+	if searched == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("every search failed, the first with: %s", errs[0])
+	}
+
+	start := getFirstLastDayofMonth(numMonths - 1).first
+	metrics, weeks, failures := doraMetrics(deployments, start, time.Now().UTC(), strings.Split(r.incidentLabels, ","))
+
+	return BuildJsonMap(infraReport, metrics, weeks, failures, errs)
+}
+
+// getFirstLastDayofMonth takes the month as input and return first day, last day and the index formatted
+// for using in report
+func getFirstLastDayofMonth(nthMonth int) date {
+	var d date
+	Time := time.Now()
+	t1 := Time.AddDate(0, -nthMonth, 0)
+	year, month, _ := t1.Date()
+	d.monthIndex = strconv.Itoa(year) + "/" + month.String()
+	d.first = time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	d.last = time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+
+	return d
+}
+
+// getPrsPerMonth searches the repository with the github Graphql api for the PRs (title, url, number)
+// merged between the first and last day provided, following the search cursor count PRs at a time
+func (r *Report) getPrsPerMonth(repo repository, date date, count int) ([]nodes, error) {
+	var query struct {
+		Search struct {
+			PageInfo struct {
+				EndCursor   githubv4.String
+				HasNextPage githubv4.Boolean
+			}
+			Nodes []nodes
+		} `graphql:"search(first: $count, after: $cursor, query: $searchQuery, type: ISSUE)"`
+	}
+
+	variables := map[string]interface{}{
+		"searchQuery": githubv4.String(fmt.Sprintf(`repo:%s/%s is:pr is:merged merged:%s..%s`, repo.Owner, repo.Name, date.first.Format("2006-01-02"), date.last.Format("2006-01-02"))),
+		"count":       githubv4.Int(count),
+		"cursor":      (*githubv4.String)(nil),
+	}
+
+	prs := make([]nodes, 0)
+	for {
+		err := r.search.Query(context.Background(), &query, variables)
+		if err != nil {
+			return nil, err
+		}
+
+		prs = append(prs, query.Search.Nodes...)
+		if !query.Search.PageInfo.HasNextPage {
+			return prs, nil
+		}
+		variables["cursor"] = githubv4.NewString(query.Search.PageInfo.EndCursor)
+	}
+}
+
+// getDeployments calls the github REST API for the changed files of each PR, and returns the PRs
+// which change a file under the paths of the repository, with the errors of the PRs whose files
+// couldn't be listed
+func (r *Report) getDeployments(repo repository, nodes []nodes) ([]Deployment, []string) {
+	deployments := make([]Deployment, 0)
+	errs := make([]string, 0)
+
+	for _, pr := range nodes {
+		deployed, err := prChangesPaths(r.files, repo, int(pr.PullRequest.Number))
+		if err != nil {
+			e := fmt.Sprintf("unable to list the files of %s: %s", pr.PullRequest.Url, err)
+			log.Println(e)
+			errs = append(errs, e)
+			continue
+		}
+		if !deployed {
+			continue
+		}
+
+		d := Deployment{
+			Repository: repo.Owner + "/" + repo.Name,
+			Number:     int(pr.PullRequest.Number),
+			Title:      string(pr.PullRequest.Title),
+			URL:        string(pr.PullRequest.Url),
+			MergedAt:   pr.PullRequest.MergedAt.Time,
+		}
+		for _, l := range pr.PullRequest.Labels.Nodes {
+			d.Labels = append(d.Labels, string(l.Name))
+		}
+		if commits := pr.PullRequest.Commits.Nodes; len(commits) > 0 {
+			d.FirstCommitAt = commits[0].Commit.AuthoredDate.Time
+		}
+
+		deployments = append(deployments, d)
+	}
+
+	return deployments, errs
+}
+
+// prChangesPaths pages through the files changed by the PR until one is under the paths of the repository
+func prChangesPaths(files fileLister, repo repository, prNumber int) (bool, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		commitFiles, resp, err := files.ListFiles(context.Background(), repo.Owner, repo.Name, prNumber, opts)
+		if err != nil {
+			return false, err
+		}
+
+		for _, file := range commitFiles {
+			if repo.matches(file.GetFilename()) {
+				return true, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return false, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// BuildJsonMap takes the monthly counts of the deployed and failed PRs, the DORA metrics of the
+// period with their weekly figures and the failures, and the errors of the searches and PRs which
+// are missing from them, and returns them json encoded
+func BuildJsonMap(infraPRs []map[string]string, metrics Metrics, weeks []Week, failures []Failure, errs []string) ([]byte, error) {
+	// To handle generics in the data type, we need to create a new map,
+	// add the first key string:string and then the second key/value string:map[string]string.
+	// As per the requirements of the HOODAW API.
+	jsonMap := resourceMap{
+		"updated_at":  time.Now().Format("2006-01-2 15:4:5 UTC"),
+		"deployments": infraPRs,
+		"metrics":     metrics,
+		"weeks":       weeks,
+		"failures":    failures,
+		"errors":      errs,
+	}
+
+	jsonStr, err := json.Marshal(jsonMap)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonStr, nil
+}
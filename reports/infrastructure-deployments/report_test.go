@@ -0,0 +1,120 @@
+package infrastructuredeployments
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_repository_matches(t *testing.T) {
+	tests := []struct {
+		name     string
+		flag     string
+		filename string
+		want     bool
+	}{
+		{"default path", defaultRepository, "terraform/aws-accounts/cloud-platform-aws/vpc/eks/main.tf", true},
+		{"outside default path", defaultRepository, "terraform/aws-accounts/cloud-platform-ephemeral-test/main.tf", false},
+		{"directory without wildcards", "repo:terraform/global-resources", "terraform/global-resources/iam.tf", true},
+		{"single star stays in a directory", "repo:terraform/*.tf", "terraform/modules/main.tf", false},
+		{"double star in the middle", "repo:terraform/**/main.tf", "terraform/main.tf", true},
+		{"second glob", "repo:terraform/**,scripts/*.sh", "scripts/deploy.sh", true},
+		{"no globs", "repo", "README.md", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := parseRepository(tt.flag)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := repo.matches(tt.filename); got != tt.want {
+				t.Errorf("matches(%q) = %v, want %v", tt.filename, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_repositories_Set(t *testing.T) {
+	repo, _ := parseRepository(defaultRepository)
+	r := repositories{repos: []repository{repo}}
+
+	for _, v := range []string{"cloud-platform-infrastructure:terraform/**", "other-org/cloud-platform-terraform-concourse"} {
+		if err := r.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(r.repos) != 2 {
+		t.Fatalf("Set() did not replace the default, got %d repositories", len(r.repos))
+	}
+	if r.repos[0].Owner != "" || r.repos[1].Owner != "other-org" || r.repos[1].Name != "cloud-platform-terraform-concourse" {
+		t.Errorf("Set() = %+v, want the owner from the flag only when given", r.repos)
+	}
+
+	if err := r.Set("other-org/:terraform/**"); err == nil {
+		t.Error("Set() expected an error for a repository without a name")
+	}
+}
+
+func Test_doraMetrics(t *testing.T) {
+	at := func(day, hour int) time.Time { return time.Date(2024, time.January, day, hour, 0, 0, 0, time.UTC) }
+
+	deployments := []Deployment{
+		{Repository: "o/a", Number: 4, Title: "Fix eks", URL: "d", FirstCommitAt: at(8, 6), MergedAt: at(8, 12)},
+		{Repository: "o/a", Number: 1, Title: "Add vpc", URL: "a", FirstCommitAt: at(1, 0), MergedAt: at(1, 10)},
+		{Repository: "o/a", Number: 2, Title: `Revert "Add vpc"`, URL: "b", FirstCommitAt: at(2, 0), MergedAt: at(2, 4)},
+		{Repository: "o/a", Number: 3, Title: "Bump eks", URL: "c", FirstCommitAt: at(8, 0), MergedAt: at(8, 6), Labels: []string{"Incident"}},
+		{Repository: "o/b", Number: 5, Title: `Revert "Old change"`, URL: "e", FirstCommitAt: at(9, 0), MergedAt: at(9, 2)},
+	}
+
+	metrics, weeks, failures := doraMetrics(deployments, at(1, 0), at(14, 0), []string{"incident"})
+
+	wantMetrics := Metrics{
+		PeriodStart:        "2024-01-01",
+		PeriodEnd:          "2024-01-14",
+		Deployments:        5,
+		Failures:           3,
+		DeploymentsPerWeek: 2.5,
+		LeadTimeHours:      6,
+		ChangeFailureRate:  0.6,
+		TimeToRestoreHours: 12,
+	}
+	wantWeeks := []Week{
+		{Start: "2024-01-01", Deployments: 2, Failures: 1, LeadTimeHours: 7},
+		{Start: "2024-01-08", Deployments: 3, Failures: 2, LeadTimeHours: 6},
+	}
+	wantFailures := []Failure{
+		{Repository: "o/b", Number: 5, Title: `Revert "Old change"`, URL: "e", MergedAt: "2024-01-09T02:00:00Z", Reason: FailureRevert},
+		{Repository: "o/a", Number: 3, Title: "Bump eks", URL: "c", MergedAt: "2024-01-08T06:00:00Z", Reason: FailureIncident, RestoredBy: "d", RestoreHours: 6},
+		{Repository: "o/a", Number: 1, Title: "Add vpc", URL: "a", MergedAt: "2024-01-01T10:00:00Z", Reason: FailureReverted, RestoredBy: "b", RestoreHours: 18},
+	}
+
+	if !reflect.DeepEqual(metrics, wantMetrics) {
+		t.Errorf("doraMetrics() metrics = %+v, want %+v", metrics, wantMetrics)
+	}
+	if !reflect.DeepEqual(weeks, wantWeeks) {
+		t.Errorf("doraMetrics() weeks = %+v, want %+v", weeks, wantWeeks)
+	}
+	if !reflect.DeepEqual(failures, wantFailures) {
+		t.Errorf("doraMetrics() failures = %+v, want %+v", failures, wantFailures)
+	}
+}
+
+func Test_weekStart(t *testing.T) {
+	tests := []struct {
+		name string
+		t    time.Time
+		want string
+	}{
+		{"monday", time.Date(2024, time.January, 8, 23, 0, 0, 0, time.UTC), "2024-01-08"},
+		{"sunday", time.Date(2024, time.January, 14, 1, 0, 0, 0, time.UTC), "2024-01-08"},
+		{"across a month", time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), "2024-02-26"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := weekStart(tt.t).Format("2006-01-02"); got != tt.want {
+				t.Errorf("weekStart() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package liveonedomains
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ministryofjustice/cloud-platform-environments/pkg/ingress"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// resourceMap is used to store both string:string and string:map[string]string key
+// value pairs. The HOODAW API requires the first entry of map to contain a string:string,
+// the rest of the map consists of a primary key (string) with a value containing a map (string:string)
+// of Kubernetes ingress resources that contain deprecated domain names.
+type resourceMap map[string]interface{}
+
+var liveOneDomain = "live-1.cloud-platform.service.justice.gov.uk"
+
+// DomainPattern is a deprecated domain, matching the domain and its subdomains unless it is a
+// regular expression, and the date by which hosts should be migrated off it
+type DomainPattern struct {
+	Pattern  string
+	Deadline string
+	regex    *regexp.Regexp
+}
+
+// matches reports whether host uses the deprecated domain
+func (p DomainPattern) matches(host string) bool {
+	if p.regex != nil {
+		return p.regex.MatchString(host)
+	}
+
+	return host == p.Pattern || strings.HasSuffix(host, "."+p.Pattern)
+}
+
+// domainPatterns is the repeatable --pattern flag, given as <domain>[=<deadline>] or
+// regex:<expression>[=<deadline>] with the deadline as YYYY-MM-DD. Neither a domain nor an
+// expression matching hosts needs an "=", so the deadline is after the first.
+type domainPatterns struct {
+	patterns []DomainPattern
+	set      bool
+}
+
+func (d *domainPatterns) String() string {
+	s := make([]string, 0)
+	for _, p := range d.patterns {
+		s = append(s, p.Pattern)
+	}
+	return strings.Join(s, ",")
+}
+
+func (d *domainPatterns) Set(value string) error {
+	// the first pattern given replaces the default
+	if !d.set {
+		d.patterns = nil
+		d.set = true
+	}
+
+	pattern, deadline := value, ""
+	if before, after, ok := strings.Cut(value, "="); ok {
+		pattern, deadline = before, after
+		if _, err := time.Parse("2006-01-02", deadline); err != nil {
+			return fmt.Errorf("invalid deadline %q, expected YYYY-MM-DD", deadline)
+		}
+	}
+
+	if pattern == "" || pattern == "regex:" {
+		return fmt.Errorf("empty pattern %q", value)
+	}
+
+	p := DomainPattern{Pattern: pattern, Deadline: deadline}
+	if expr, ok := strings.CutPrefix(pattern, "regex:"); ok {
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		p.regex = regex
+	}
+
+	d.patterns = append(d.patterns, p)
+	return nil
+}
+
+// Report lists the ingress hosts in each cluster which still use a deprecated domain, by
+// default the live-1 domain.
+type Report struct {
+	bucket      string
+	contexts    utils.Contexts
+	kubeconfig  string
+	kubeCfgPath string
+	patterns    domainPatterns
+	region      string
+}
+
+// New returns the live-one-domains report
+func New() *Report {
+	return &Report{}
+}
+
+func (r *Report) Name() string { return "live-one-domains" }
+
+func (r *Report) Key() string { return "live_one_domains.json" }
+
+func (r *Report) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&r.bucket, "bucket", os.Getenv("KUBECONFIG_S3_BUCKET"), "AWS S3 bucket for kubeconfig")
+	r.contexts = utils.Contexts{"live.cloud-platform.service.justice.gov.uk"}
+	fs.Var(&r.contexts, "context", "Comma separated list of Kubernetes contexts specified in kubeconfig")
+	fs.StringVar(&r.kubeconfig, "kubeconfig", "kubeconfig", "Name of kubeconfig file in S3 bucket")
+	fs.StringVar(&r.kubeCfgPath, "kubeCfgPath", os.Getenv("KUBECONFIG"), "Path of the kube config file, used when no bucket is set")
+	r.patterns = domainPatterns{patterns: []DomainPattern{{Pattern: liveOneDomain}}}
+	fs.Var(&r.patterns, "pattern", "Deprecated domain as <domain>[=<YYYY-MM-DD deadline>] or regex:<expression>[=<deadline>], can be repeated")
+	fs.StringVar(&r.region, "region", os.Getenv("AWS_REGION"), "AWS Region")
+}
+
+func (r *Report) Generate() ([]byte, error) {
+	domains := make([]map[string]string, 0)
+
+	for _, ctx := range r.contexts {
+		cluster := utils.ClusterName(ctx)
+
+		// Gain access to a Kubernetes cluster using a config file stored in an S3 bucket.
+		clientset, err := utils.KubeClient(r.bucket, r.kubeconfig, r.region, r.kubeCfgPath, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to authenticate to the %s cluster: %s", cluster, err)
+		}
+
+		// Get all ingress resources
+		domainSearch, err := ingress.GetAllIngressesFromCluster(clientset)
+		if err != nil {
+			return nil, fmt.Errorf("unable to return Ingress List from the %s cluster: %s", cluster, err)
+		}
+
+		log.Printf("Searching for deprecated domains in ingress resources of the %s cluster completed\n", cluster)
+
+		// Find all ingress resources with a deprecated domain name
+		domains = append(domains, deprecatedDomainSearch(cluster, r.patterns.patterns, domainSearch)...)
+	}
+
+	// Build the json map
+	jsonToPost, err := buildJsonMap(domains)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build json map: %s", err)
+	}
+
+	return jsonToPost, nil
+}
+
+// deprecatedDomainSearch searches list created by GetAllIngresses for all ingress resources and returns a
+// list of cluster, namespace, ingress resources and the rule or TLS hosts that are still using a deprecated
+// domain name, with the pattern they matched and its deadline
+func deprecatedDomainSearch(cluster string, patterns []DomainPattern, domainSearch *networkingv1.IngressList) []map[string]string {
+	// s contains a slice of maps, each map will be iterated over when placed in a dashboard.
+	s := make([]map[string]string, 0)
+
+	for _, domain := range domainSearch.Items {
+		hosts := make([]string, 0)
+		for _, rule := range domain.Spec.Rules {
+			hosts = append(hosts, rule.Host)
+		}
+		for _, tls := range domain.Spec.TLS {
+			hosts = append(hosts, tls.Hosts...)
+		}
+
+		seen := make(map[string]bool)
+		for _, host := range hosts {
+			if host == "" || seen[host] {
+				continue
+			}
+			seen[host] = true
+
+			for _, p := range patterns {
+				if !p.matches(host) {
+					continue
+				}
+
+				ingress := map[string]string{
+					"cluster":   cluster,
+					"namespace": domain.Namespace,
+					"ingress":   domain.Name,
+					"hostname":  host,
+					"pattern":   p.Pattern,
+					"deadline":  p.Deadline,
+					"CreatedAt": domain.CreationTimestamp.Format("2006-01-2 15:4:5 UTC"),
+				}
+				s = append(s, ingress)
+				break
+			}
+		}
+	}
+	return s
+}
+
+// BuildJsonMap takes a slice of maps and return a json encoded map
+func buildJsonMap(ingress []map[string]string) ([]byte, error) {
+	// To handle generics in the data type, we need to create a new map,
+	// add the first key string:string and then the second key/value string:map[string]string.
+	// As per the requirements of the HOODAW API.
+	jsonMap := resourceMap{
+		"updated_at":       time.Now().Format("2006-01-2 15:4:5 UTC"),
+		"live_one_domains": ingress,
+	}
+
+	jsonStr, err := json.MarshalIndent(jsonMap, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonStr, nil
+}
@@ -0,0 +1,199 @@
+package liveonedomains
+
+import (
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
+)
+
+func Test_deprecatedDomainSearch(t *testing.T) {
+	var patterns domainPatterns
+	for _, p := range []string{liveOneDomain + "=2021-06-30", `regex:^legacy-[a-z]+\.service\.justice\.gov\.uk$`} {
+		if err := patterns.Set(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type args struct {
+		cluster      string
+		domainSearch *networkingv1.IngressList
+	}
+	tests := []struct {
+		name string
+		args args
+		want []map[string]string
+	}{
+		{
+			name: "live1DomainSearch-Success",
+			args: args{
+				cluster: "live",
+				domainSearch: &networkingv1.IngressList{
+					Items: []networkingv1.Ingress{
+						{
+							ObjectMeta: metav1.ObjectMeta{
+								Namespace: "namespace-1",
+								Name:      "ingress-1",
+							},
+							Spec: networkingv1.IngressSpec{
+								Rules: []networkingv1.IngressRule{
+									{
+										Host: "example.live-1.cloud-platform.service.justice.gov.uk",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: []map[string]string{
+				{
+					"cluster":   "live",
+					"CreatedAt": "0001-01-1 00:0:0 UTC",
+					"hostname":  "example.live-1.cloud-platform.service.justice.gov.uk",
+					"namespace": "namespace-1",
+					"ingress":   "ingress-1",
+					"pattern":   "live-1.cloud-platform.service.justice.gov.uk",
+					"deadline":  "2021-06-30",
+				},
+			},
+		},
+		{
+			name: "TLS and regex hosts",
+			args: args{
+				cluster: "live",
+				domainSearch: &networkingv1.IngressList{
+					Items: []networkingv1.Ingress{
+						{
+							ObjectMeta: metav1.ObjectMeta{
+								Namespace: "namespace-1",
+								Name:      "ingress-1",
+							},
+							Spec: networkingv1.IngressSpec{
+								Rules: []networkingv1.IngressRule{
+									{
+										Host: "legacy-app.service.justice.gov.uk",
+									},
+								},
+								TLS: []networkingv1.IngressTLS{
+									{
+										Hosts: []string{
+											"legacy-app.service.justice.gov.uk",
+											"tls.live-1.cloud-platform.service.justice.gov.uk",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: []map[string]string{
+				{
+					"cluster":   "live",
+					"CreatedAt": "0001-01-1 00:0:0 UTC",
+					"hostname":  "legacy-app.service.justice.gov.uk",
+					"namespace": "namespace-1",
+					"ingress":   "ingress-1",
+					"pattern":   `regex:^legacy-[a-z]+\.service\.justice\.gov\.uk$`,
+					"deadline":  "",
+				},
+				{
+					"cluster":   "live",
+					"CreatedAt": "0001-01-1 00:0:0 UTC",
+					"hostname":  "tls.live-1.cloud-platform.service.justice.gov.uk",
+					"namespace": "namespace-1",
+					"ingress":   "ingress-1",
+					"pattern":   "live-1.cloud-platform.service.justice.gov.uk",
+					"deadline":  "2021-06-30",
+				},
+			},
+		},
+		{
+			name: "live1DomainSearch-Error",
+			args: args{
+				cluster: "live",
+				domainSearch: &networkingv1.IngressList{
+					Items: []networkingv1.Ingress{
+						{
+							ObjectMeta: metav1.ObjectMeta{
+								Namespace: "namespace-1",
+								Name:      "ingress-1",
+							},
+							Spec: networkingv1.IngressSpec{
+								Rules: []networkingv1.IngressRule{
+									{
+										Host: "example.live.cloud-platform.service.justice.gov.uk",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: []map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := deprecatedDomainSearch(tt.args.cluster, patterns.patterns, tt.args.domainSearch)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deprecatedDomainSearch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_domainPatterns_Set(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"suffix", "apps.live.cloud-platform.service.justice.gov.uk", false},
+		{"suffix with deadline", "apps.live.cloud-platform.service.justice.gov.uk=2025-03-31", false},
+		{"regex", `regex:^.*\.apps\.live-1\..*$=2025-03-31`, false},
+		{"invalid deadline", "apps.live.cloud-platform.service.justice.gov.uk=31/03/2025", true},
+		{"invalid regex", "regex:(", true},
+		{"empty", "=2025-03-31", true},
+		{"deadline after the first =", "apps.live.cloud-platform.service.justice.gov.uk=2025-03-31=2025-04-30", true},
+		{"empty deadline", "apps.live.cloud-platform.service.justice.gov.uk=", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := domainPatterns{patterns: []DomainPattern{{Pattern: liveOneDomain}}}
+			err := d.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("domainPatterns.Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(d.patterns) != 1 {
+				t.Errorf("domainPatterns.Set() did not replace the default, got %v", d.patterns)
+			}
+		})
+	}
+}
+
+func TestDomainPattern_matches(t *testing.T) {
+	p := DomainPattern{Pattern: "service.justice.gov.uk"}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"service.justice.gov.uk", true},
+		{"app.service.justice.gov.uk", true},
+		{"app.legacy.service.justice.gov.uk", true},
+		{"myservice.justice.gov.uk", false},
+		{"service.justice.gov.uk.example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := p.matches(tt.host); got != tt.want {
+				t.Errorf("DomainPattern.matches(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
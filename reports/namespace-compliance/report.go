@@ -0,0 +1,182 @@
+package namespacecompliance
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ministryofjustice/cloud-platform-environments/pkg/namespace"
+	hostedservices "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/hosted-services"
+	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+)
+
+// businessUnits are the business units accepted by the cloud-platform cli when a namespace is created
+const businessUnits = "CICA,HMCTS,HMPPS,LAA,OPG,Platforms,HQ"
+
+// githubURL matches the repository of a source-code annotation, e.g. https://github.com/ministryofjustice/repo
+var githubURL = regexp.MustCompile(`^https://github\.com/[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+/?$`)
+
+// resourceMap is used to store both string:string and string:[]NamespaceCompliance key
+// value pairs. As per the requirements of the HOODAW API.
+type resourceMap map[string]interface{}
+
+// NamespaceCompliance holds the problems found with the annotations of a namespace
+type NamespaceCompliance struct {
+	Name             string
+	Cluster          string
+	TeamName         string
+	TeamSlackChannel string
+	Issues           []string
+}
+
+// Report lists the namespaces in each cluster which are missing or have invalid
+// cloud-platform.justice.gov.uk annotations.
+type Report struct {
+	bucket        string
+	businessUnits string
+	contexts      utils.Contexts
+	exclude       string
+	kubeconfig    string
+	kubeCfgPath   string
+	region        string
+}
+
+// New returns the namespace-compliance report
+func New() *Report {
+	return &Report{}
+}
+
+func (r *Report) Name() string { return "namespace-compliance" }
+
+func (r *Report) Key() string { return "namespace_compliance.json" }
+
+func (r *Report) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&r.bucket, "bucket", os.Getenv("KUBECONFIG_S3_BUCKET"), "AWS S3 bucket for kubeconfig")
+	fs.StringVar(&r.businessUnits, "business-units", businessUnits, "Comma separated list of the known business units")
+	r.contexts = utils.Contexts{"live.cloud-platform.service.justice.gov.uk"}
+	fs.Var(&r.contexts, "context", "Comma separated list of Kubernetes contexts specified in kubeconfig")
+	fs.StringVar(&r.exclude, "exclude", "default,kube-node-lease,kube-public,kube-system", "Comma separated list of namespaces which are not checked")
+	fs.StringVar(&r.kubeconfig, "kubeconfig", "kubeconfig", "Name of kubeconfig file in S3 bucket")
+	fs.StringVar(&r.kubeCfgPath, "kubeCfgPath", os.Getenv("KUBECONFIG"), "Path of the kube config file, used when no bucket is set")
+	fs.StringVar(&r.region, "region", os.Getenv("AWS_REGION"), "AWS Region")
+}
+
+func (r *Report) Generate() ([]byte, error) {
+	known := listToSet(r.businessUnits)
+	excluded := listToSet(r.exclude)
+
+	checked := 0
+	nonCompliant := make([]NamespaceCompliance, 0)
+
+	for _, ctx := range r.contexts {
+		cluster := utils.ClusterName(ctx)
+
+		clientset, err := utils.KubeClient(r.bucket, r.kubeconfig, r.region, r.kubeCfgPath, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to authenticate to the %s cluster: %w", cluster, err)
+		}
+
+		namespaces, err := namespace.GetAllNamespacesFromCluster(clientset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ns := range namespaces {
+			if excluded[ns.Name] {
+				continue
+			}
+			checked++
+
+			details := hostedservices.GetNamespaceDetails(ns)
+			if issues := checkNamespace(details, known); len(issues) > 0 {
+				nonCompliant = append(nonCompliant, NamespaceCompliance{
+					Name:             details.Name,
+					Cluster:          cluster,
+					TeamName:         details.TeamName,
+					TeamSlackChannel: details.TeamSlackChannel,
+					Issues:           issues,
+				})
+			}
+		}
+	}
+
+	sort.Slice(nonCompliant, func(i, j int) bool {
+		if nonCompliant[i].Cluster != nonCompliant[j].Cluster {
+			return nonCompliant[i].Cluster < nonCompliant[j].Cluster
+		}
+		return nonCompliant[i].Name < nonCompliant[j].Name
+	})
+
+	return buildJsonMap(nonCompliant, checked)
+}
+
+// checkNamespace returns the problems with the annotations of a namespace, read by
+// GetNamespaceDetails, given the set of known business units
+func checkNamespace(ns namespace.Namespace, businessUnits map[string]bool) []string {
+	issues := make([]string, 0)
+
+	required := []struct {
+		annotation string
+		value      string
+	}{
+		{"application", ns.Application},
+		{"business-unit", ns.BusinessUnit},
+		{"team-name", ns.TeamName},
+		{"slack-channel", ns.TeamSlackChannel},
+		{"source-code", ns.GithubURL},
+	}
+
+	for _, r := range required {
+		if strings.TrimSpace(r.value) == "" {
+			issues = append(issues, "missing "+r.annotation+" annotation")
+		}
+	}
+
+	if ns.BusinessUnit != "" && !businessUnits[ns.BusinessUnit] {
+		issues = append(issues, "unknown business unit "+ns.BusinessUnit)
+	}
+
+	// the source-code annotation can list more than one repository
+	if ns.GithubURL != "" {
+		for _, url := range strings.Split(ns.GithubURL, ",") {
+			if url = strings.TrimSpace(url); !githubURL.MatchString(url) {
+				issues = append(issues, "invalid GitHub URL "+url)
+			}
+		}
+	}
+
+	return issues
+}
+
+// listToSet splits a comma separated list into a set
+func listToSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			set[v] = true
+		}
+	}
+	return set
+}
+
+// buildJsonMap takes the non compliant namespaces and the number of namespaces checked
+// and return a json encoded map
+func buildJsonMap(namespaces []NamespaceCompliance, checked int) ([]byte, error) {
+	jsonMap := resourceMap{
+		"updated_at":           time.Now().Format("2006-01-2 15:4:5 UTC"),
+		"namespace_compliance": namespaces,
+		"namespaces_checked":   checked,
+	}
+
+	jsonStr, err := json.Marshal(jsonMap)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonStr, nil
+}
@@ -0,0 +1,64 @@
+package namespacecompliance
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ministryofjustice/cloud-platform-environments/pkg/namespace"
+)
+
+func Test_checkNamespace(t *testing.T) {
+	known := listToSet(businessUnits)
+
+	tests := []struct {
+		name string
+		ns   namespace.Namespace
+		want []string
+	}{
+		{
+			name: "compliant namespace",
+			ns: namespace.Namespace{
+				Application:      "app",
+				BusinessUnit:     "HMPPS",
+				TeamName:         "team",
+				TeamSlackChannel: "#team",
+				GithubURL:        "https://github.com/ministryofjustice/app, https://github.com/ministryofjustice/app-infra/",
+			},
+			want: []string{},
+		},
+		{
+			name: "missing annotations",
+			ns: namespace.Namespace{
+				Application:  "app",
+				BusinessUnit: "LAA",
+				TeamName:     " ",
+			},
+			want: []string{
+				"missing team-name annotation",
+				"missing slack-channel annotation",
+				"missing source-code annotation",
+			},
+		},
+		{
+			name: "invalid values",
+			ns: namespace.Namespace{
+				Application:      "app",
+				BusinessUnit:     "Digital",
+				TeamName:         "team",
+				TeamSlackChannel: "#team",
+				GithubURL:        "github.com/ministryofjustice/app",
+			},
+			want: []string{
+				"unknown business unit Digital",
+				"invalid GitHub URL github.com/ministryofjustice/app",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkNamespace(tt.ns, known); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkNamespace() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
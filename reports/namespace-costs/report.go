@@ -1,11 +1,10 @@
-package main
+package namespacecosts
 
 import (
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
 	"math"
 	"os"
 	"strconv"
@@ -16,18 +15,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
 	ceTypes "github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
-	"github.com/ministryofjustice/cloud-platform-environments/pkg/authenticate"
 	"github.com/ministryofjustice/cloud-platform-environments/pkg/namespace"
 	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
-	v1 "k8s.io/api/core/v1"
-)
-
-var (
-	hoodawBucket = flag.String("hoodaw-bucket", os.Getenv("HOODAW_BUCKET"), "AWS S3 bucket for hoodaw json reports")
-	bucket       = flag.String("bucket", os.Getenv("KUBECONFIG_S3_BUCKET"), "AWS S3 bucket for kubeconfig")
-	ctx          = flag.String("context", "live.cloud-platform.service.justice.gov.uk", "Kubernetes context specified in kubeconfig")
-	kubeconfig   = flag.String("kubeconfig", "kubeconfig", "Name of kubeconfig file in S3 bucket")
-	region       = flag.String("region", os.Getenv("AWS_REGION"), "AWS Region")
 )
 
 const SHARED_COSTS string = "SHARED_COSTS"
@@ -52,17 +41,52 @@ type costs struct {
 	costPerNamespace map[string]map[string]float64
 }
 
-func main() {
-	flag.Parse()
+// Report works out the running costs of each namespace in the clusters.
+type Report struct {
+	bucket      string
+	contexts    utils.Contexts
+	kubeconfig  string
+	kubeCfgPath string
+	region      string
+}
 
-	clientset, err := authenticate.CreateClientFromS3Bucket(*bucket, *kubeconfig, *region, *ctx)
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
+// New returns the namespace-costs report
+func New() *Report {
+	return &Report{}
+}
 
-	namespaces, err := namespace.GetAllNamespacesFromCluster(clientset)
-	if err != nil {
-		log.Fatalln(err.Error())
+func (r *Report) Name() string { return "namespace-costs" }
+
+func (r *Report) Key() string { return "namespace_costs.json" }
+
+func (r *Report) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&r.bucket, "bucket", os.Getenv("KUBECONFIG_S3_BUCKET"), "AWS S3 bucket for kubeconfig")
+	r.contexts = utils.Contexts{"live.cloud-platform.service.justice.gov.uk"}
+	fs.Var(&r.contexts, "context", "Comma separated list of Kubernetes contexts specified in kubeconfig")
+	fs.StringVar(&r.kubeconfig, "kubeconfig", "kubeconfig", "Name of kubeconfig file in S3 bucket")
+	fs.StringVar(&r.kubeCfgPath, "kubeCfgPath", os.Getenv("KUBECONFIG"), "Path of the kube config file, used when no bucket is set")
+	fs.StringVar(&r.region, "region", os.Getenv("AWS_REGION"), "AWS Region")
+}
+
+func (r *Report) Generate() ([]byte, error) {
+	// Costs are grouped by the namespace tag across the whole AWS account, so a namespace
+	// name used in more than one cluster has a single entry tagged with all its clusters
+	nsClusters := make(map[string][]string)
+
+	for _, ctx := range r.contexts {
+		clientset, err := utils.KubeClient(r.bucket, r.kubeconfig, r.region, r.kubeCfgPath, ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		namespaces, err := namespace.GetAllNamespacesFromCluster(clientset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ns := range namespaces {
+			nsClusters[ns.Name] = append(nsClusters[ns.Name], utils.ClusterName(ctx))
+		}
 	}
 
 	c := &costs{
@@ -71,20 +95,20 @@ func main() {
 
 	awsCostUsageData, err := getAwsCostAndUsageData()
 	if err != nil {
-		log.Fatalln(err.Error())
+		return nil, err
 	}
 
 	// create the resources map for namespaces which are listed in the cluster
 	// This is needed later to update shared costs for namespaces which doesnot have any aws resources
-	for _, ns := range namespaces {
+	for ns := range nsClusters {
 		resources := make(map[string]float64)
-		c.costPerNamespace[ns.Name] = resources
+		c.costPerNamespace[ns] = resources
 	}
 
 	// update the costs per namespace in a map for all aws resources from CostUsage data
 	err = c.updatecostsByNamespace(awsCostUsageData)
 	if err != nil {
-		log.Fatalln(err.Error())
+		return nil, err
 	}
 
 	// add shared aws resources costs i.e resources which doesnot have namespace tags but global
@@ -93,31 +117,9 @@ func main() {
 
 	c.addSharedTeamCosts()
 
-	namespacesMap := c.buildCostsResourceMap(namespaces)
+	namespacesMap := c.buildCostsResourceMap(nsClusters)
 
-	jsonToPost, err := BuildJsonMap(namespacesMap)
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
-
-	client, err := utils.S3Client("eu-west-2")
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
-
-	b, err := utils.CheckBucketExists(client, *hoodawBucket)
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
-
-	if !b {
-		log.Fatalf("Bucket %s does not exist\n", *hoodawBucket)
-	}
-
-	exportErr := utils.ExportToS3(client, *hoodawBucket, "namespace_costs.json", jsonToPost)
-	if exportErr != nil {
-		log.Fatalln(exportErr.Error())
-	}
+	return BuildJsonMap(namespacesMap)
 }
 
 // getAwsCostAndUsageData get the data from aws cost explorer api and build a slice of [date,resourcename,namespacename,cost]
@@ -242,13 +244,13 @@ func (c *costs) addSharedTeamCosts() error {
 	return nil
 }
 
-// buildCostsResourceMap build the resources Map for all namespaces
-// with the format required by HOODAW frontend
-func (c *costs) buildCostsResourceMap(nsList []v1.Namespace) resourceMap {
+// buildCostsResourceMap build the resources Map for all namespaces, tagged with
+// the clusters they are in, with the format required by HOODAW frontend
+func (c *costs) buildCostsResourceMap(nsClusters map[string][]string) resourceMap {
 	namespaces := make(map[string]interface{}, 0)
 
-	for _, ns := range nsList {
-		breakdown := c.costPerNamespace[ns.Name]
+	for ns, clusters := range nsClusters {
+		breakdown := c.costPerNamespace[ns]
 
 		var total float64 = 0
 		m := breakdown
@@ -257,9 +259,10 @@ func (c *costs) buildCostsResourceMap(nsList []v1.Namespace) resourceMap {
 		}
 
 		total = math.Round(total*100) / 100
-		namespaces[ns.Name] = resourceMap{
+		namespaces[ns] = resourceMap{
 			"breakdown": breakdown,
 			"total":     total,
+			"clusters":  clusters,
 		}
 
 	}
@@ -1,11 +1,8 @@
-package main
+package namespacecosts
 
 import (
 	"reflect"
 	"testing"
-
-	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func Test_costs_updatecostsByNamespace(t *testing.T) {
@@ -110,7 +107,7 @@ func Test_costs_buildCostsResourceMap(t *testing.T) {
 		costPerNamespace map[string]map[string]float64
 	}
 	type args struct {
-		nsList []v1.Namespace
+		nsClusters map[string][]string
 	}
 	tests := []struct {
 		name   string
@@ -132,12 +129,8 @@ func Test_costs_buildCostsResourceMap(t *testing.T) {
 				},
 			},
 			args: args{
-				nsList: []v1.Namespace{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "ns1",
-						},
-					},
+				nsClusters: map[string][]string{
+					"ns1": {"live", "manager"},
 				},
 			},
 			want: resourceMap{
@@ -146,7 +139,8 @@ func Test_costs_buildCostsResourceMap(t *testing.T) {
 						"service 1": 16.40,
 						"service 2": 1.40,
 					},
-					"total": 17.80,
+					"total":    17.80,
+					"clusters": []string{"live", "manager"},
 				},
 			},
 		},
@@ -157,7 +151,7 @@ func Test_costs_buildCostsResourceMap(t *testing.T) {
 			c := &costs{
 				costPerNamespace: tt.fields.costPerNamespace,
 			}
-			if got := c.buildCostsResourceMap(tt.args.nsList); !reflect.DeepEqual(got, tt.want) {
+			if got := c.buildCostsResourceMap(tt.args.nsClusters); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("costs.buildCostsResourceMap() = %v, want %v", got, tt.want)
 			}
 		})
@@ -1,15 +1,13 @@
-package main
+package namespaceusage
 
 import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"strconv"
 	"time"
 
-	"github.com/ministryofjustice/cloud-platform-environments/pkg/authenticate"
 	auth "github.com/ministryofjustice/cloud-platform-environments/pkg/authenticate"
 	ns "github.com/ministryofjustice/cloud-platform-environments/pkg/namespace"
 	"github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
@@ -20,15 +18,6 @@ import (
 	"k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
-var (
-	hoodawBucket = flag.String("howdaw-bucket", os.Getenv("HOODAW_BUCKET"), "AWS S3 bucket for hoodaw json reports")
-	bucket       = flag.String("bucket", os.Getenv("KUBECONFIG_S3_BUCKET"), "AWS S3 bucket for kubeconfig")
-	ctx          = flag.String("context", "live.cloud-platform.service.justice.gov.uk", "Kubernetes context specified in kubeconfig")
-	kubeconfig   = flag.String("kubeconfig", "kubeconfig", "Name of kubeconfig file in S3 bucket")
-	region       = flag.String("region", os.Getenv("AWS_REGION"), "AWS Region")
-	kubeCfgPath  = flag.String("kubeCfgPath", os.Getenv("KUBECONFIG"), "Path of the kube config file")
-)
-
 // NamespaceResource has the type of resource info
 // being collected per namespace by this report
 type NamespaceResource struct {
@@ -46,45 +35,86 @@ type UsageReport struct {
 	Hardlimits     NamespaceResource
 	ContainerCount int
 	Name           string
+	Cluster        string
+}
+
+// Report gathers the requested, used and hard limit resources of each namespace in each cluster.
+type Report struct {
+	bucket      string
+	contexts    utils.Contexts
+	kubeconfig  string
+	kubeCfgPath string
+	region      string
+}
+
+// New returns the namespace-usage report
+func New() *Report {
+	return &Report{}
+}
+
+func (r *Report) Name() string { return "namespace-usage" }
+
+func (r *Report) Key() string { return "namespace_usage.json" }
+
+func (r *Report) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&r.bucket, "bucket", os.Getenv("KUBECONFIG_S3_BUCKET"), "AWS S3 bucket for kubeconfig")
+	r.contexts = utils.Contexts{"live.cloud-platform.service.justice.gov.uk"}
+	fs.Var(&r.contexts, "context", "Comma separated list of Kubernetes contexts specified in kubeconfig")
+	fs.StringVar(&r.kubeconfig, "kubeconfig", "kubeconfig", "Name of kubeconfig file in S3 bucket")
+	fs.StringVar(&r.kubeCfgPath, "kubeCfgPath", os.Getenv("KUBECONFIG"), "Path of the kube config file")
+	fs.StringVar(&r.region, "region", os.Getenv("AWS_REGION"), "AWS Region")
 }
 
-func main() {
-	flag.Parse()
+func (r *Report) Generate() ([]byte, error) {
+	var usageReports []UsageReport
+
+	for _, ctx := range r.contexts {
+		reports, err := r.clusterUsage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error in getting the usage of the %s cluster: %w", utils.ClusterName(ctx), err)
+		}
+		usageReports = append(usageReports, reports...)
+	}
 
+	return buildJsonMap(usageReports)
+}
+
+// clusterUsage builds the usageReport of each namespace in the cluster of the kube context
+func (r *Report) clusterUsage(ctx string) ([]UsageReport, error) {
 	// Get the kubeconfig file stored in an S3 bucket.
-	clientset, err := authenticate.CreateClientFromS3Bucket(*bucket, *kubeconfig, *region, *ctx)
+	clientset, err := utils.KubeClient(r.bucket, r.kubeconfig, r.region, r.kubeCfgPath, ctx)
 	if err != nil {
-		log.Fatalln(err.Error())
+		return nil, err
 	}
 
 	// Get the clientset object to access cluster metrics
-	mclientset, err := auth.CreateMetricsClientFromConfigFile(*kubeCfgPath, *ctx)
+	mclientset, err := auth.CreateMetricsClientFromConfigFile(r.kubeCfgPath, ctx)
 	if err != nil {
-		log.Fatalln("error in creating metrics clientset", err.Error())
+		return nil, fmt.Errorf("error in creating metrics clientset: %s", err)
 	}
 
 	// Get the list of namespaces from the cluster which is set in the kclientset
 	nsList, err := ns.GetAllNamespacesFromCluster(clientset)
 	if err != nil {
-		log.Fatalln("error in getting all namespaces from cluster", err.Error())
+		return nil, fmt.Errorf("error in getting all namespaces from cluster: %s", err)
 	}
 
 	// Get pod requests requests and container count of all namespaces of a given cluster
 	nsReqMap, containerMap, err := getAllPodResourceDetails(clientset)
 	if err != nil {
-		log.Fatalln("error in getting all pod resources details", err.Error())
+		return nil, fmt.Errorf("error in getting all pod resources details: %s", err)
 	}
 
 	// Get pod usage resources of all namespaces of a given cluster
 	nsUsedMap, err := getAllPodMetricsesDetails(mclientset)
 	if err != nil {
-		log.Fatalln("error in getting all pod metrics details", err.Error())
+		return nil, fmt.Errorf("error in getting all pod metrics details: %s", err)
 	}
 
 	// Get hard limit of pods of all namespaces of a given cluster
 	nsQuotaMap, err := getAllResourceQuotaDetails(clientset)
 	if err != nil {
-		log.Fatalln("error in getting all resourcequota details", err.Error())
+		return nil, fmt.Errorf("error in getting all resourcequota details: %s", err)
 	}
 
 	var usageReports []UsageReport
@@ -92,6 +122,7 @@ func main() {
 	for _, ns := range nsList {
 		usageReport := UsageReport{
 			Name:           ns.Name,
+			Cluster:        utils.ClusterName(ctx),
 			Requested:      nsReqMap[ns.Name],
 			Used:           nsUsedMap[ns.Name],
 			Hardlimits:     nsQuotaMap[ns.Name],
@@ -100,30 +131,7 @@ func main() {
 		usageReports = append(usageReports, usageReport)
 	}
 
-	jsonToPost, err := buildJsonMap(usageReports)
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
-
-	// Post json to S3
-	client, err := utils.S3Client("eu-west-2")
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
-
-	b, err := utils.CheckBucketExists(client, *hoodawBucket)
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
-
-	if !b {
-		log.Fatalf("Bucket %s does not exist\n", *hoodawBucket)
-	}
-
-	utils.ExportToS3(client, *hoodawBucket, "namespace_usage.json", jsonToPost)
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
+	return usageReports, nil
 }
 
 // getAllPodResourceDetails takes a clientset and return Pod resource details
@@ -201,7 +209,7 @@ func getAllResourceQuotaDetails(kclientset kubernetes.Interface) (
 	for _, rsQuota := range rsQuotasList {
 		hardLimits, namespace, err := getPodHardLimits(rsQuota)
 		if err != nil {
-			log.Fatalln(err.Error())
+			return nil, err
 		}
 		nsQuotaMap[namespace] = hardLimits
 	}
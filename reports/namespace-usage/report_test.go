@@ -1,4 +1,4 @@
-package main
+package namespaceusage
 
 import (
 	"reflect"
@@ -12,7 +12,7 @@ import (
 	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
 )
 
-func TestgetPodResourceDetails(t *testing.T) {
+func Test_getPodResourceDetails(t *testing.T) {
 	type args struct {
 		pod v1.Pod
 	}
@@ -75,7 +75,7 @@ func TestgetPodResourceDetails(t *testing.T) {
 	}
 }
 
-func TestgetPodUsageDetails(t *testing.T) {
+func Test_getPodUsageDetails(t *testing.T) {
 	type args struct {
 		podMetrics v1beta1.PodMetrics
 	}
@@ -123,7 +123,7 @@ func TestgetPodUsageDetails(t *testing.T) {
 	}
 }
 
-func TestgetPodHardLimits(t *testing.T) {
+func Test_getPodHardLimits(t *testing.T) {
 	type args struct {
 		resourceQuota corev1.ResourceQuota
 	}
@@ -0,0 +1,302 @@
+package hoodaw
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultURL is the base URL of the production HOODAW API
+const DefaultURL = "https://reports.cloud-platform.service.justice.gov.uk/"
+
+// Client gets the reports from the HOODAW API, and posts them to it with the APIKey. Gets which
+// fail to connect or get a 429 or 5xx response are retried up to Retries times, waiting for
+// Backoff then doubling it each time, or for as long as the Retry-After header of the response says.
+// A post may have been written by the time it fails, so it is only retried when it failed before
+// the request was sent.
+type Client struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+	Retries    int
+	Backoff    time.Duration
+	UserAgent  string
+}
+
+// StatusError is returned for a response which isn't a 2xx
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s returned %s: %s", e.Method, e.URL, e.Status, e.Body)
+}
+
+// NewClient returns a client of the HOODAW API at baseURL, with a 30 second timeout and 3 retries
+// from a second of backoff
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    baseURL,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		Retries:    3,
+		Backoff:    time.Second,
+		UserAgent:  "hoodaw-pkg",
+	}
+}
+
+// HostedServices returns the hosted services report of the namespaces and their applications
+func (c *Client) HostedServices(ctx context.Context) (*HostedServices, error) {
+	var r HostedServices
+	if err := c.GetJSON(ctx, "hosted_services", &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// HelmReleases returns the helm releases of each cluster with their latest versions
+func (c *Client) HelmReleases(ctx context.Context) (*HelmReleases, error) {
+	var r HelmReleases
+	if err := c.GetJSON(ctx, "helm_whatup", &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// NamespaceCosts returns the AWS costs of each namespace
+func (c *Client) NamespaceCosts(ctx context.Context) (*Costs, error) {
+	var r Costs
+	if err := c.GetJSON(ctx, "costs_by_namespace", &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ErroringNamespaces returns the namespaces which failed in the last apply-live run
+func (c *Client) ErroringNamespaces(ctx context.Context) ([]NamespaceError, error) {
+	var r []NamespaceError
+	if err := c.GetJSON(ctx, "erroring_namespaces", &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ErroringNamespacesHistory returns the namespaces which failed in each of the recent apply-live
+// runs, with how long each has been failing
+func (c *Client) ErroringNamespacesHistory(ctx context.Context) (*ErroringNamespacesHistory, error) {
+	var r ErroringNamespacesHistory
+	if err := c.GetJSON(ctx, "reports/erroring_namespaces_history", &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// NamespaceUsage returns the requested, used and hard limit resources of each namespace
+func (c *Client) NamespaceUsage(ctx context.Context) (*NamespaceUsage, error) {
+	var r NamespaceUsage
+	if err := c.GetJSON(ctx, "reports/namespace_usage", &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// LiveOneDomains returns the domains still to be migrated off the live-1 cluster
+func (c *Client) LiveOneDomains(ctx context.Context) (*Domains, error) {
+	var r Domains
+	if err := c.GetJSON(ctx, "live_one_domains", &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// DomainReachability returns the domain names of the hosted services with whether they resolve
+// and respond, and the number which are dangling
+func (c *Client) DomainReachability(ctx context.Context) (*DomainReachability, error) {
+	var r DomainReachability
+	if err := c.GetJSON(ctx, "reports/domain_reachability", &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// NamespaceCompliance returns the namespaces missing the required annotations
+func (c *Client) NamespaceCompliance(ctx context.Context) (*NamespaceCompliance, error) {
+	var r NamespaceCompliance
+	if err := c.GetJSON(ctx, "namespace_compliance", &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CertificateExpiry returns the TLS certificates of the ingress hosts
+func (c *Client) CertificateExpiry(ctx context.Context) (*CertificateExpiry, error) {
+	var r CertificateExpiry
+	if err := c.GetJSON(ctx, "certificate_expiry", &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// InfrastructureDeployments returns the infrastructure deployments with their DORA metrics
+func (c *Client) InfrastructureDeployments(ctx context.Context) (*InfrastructureDeployments, error) {
+	var r InfrastructureDeployments
+	if err := c.GetJSON(ctx, "infrastructure_deployments", &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetJSON gets the json of the endpoint and decodes it into v
+func (c *Client) GetJSON(ctx context.Context, endpoint string, v interface{}) error {
+	body, err := c.Get(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("unable to decode the json of %s: %w", endpoint, err)
+	}
+
+	return nil
+}
+
+// Get returns the json body of the endpoint, a path under the base URL or an absolute URL
+func (c *Client) Get(ctx context.Context, endpoint string) ([]byte, error) {
+	return c.do(ctx, http.MethodGet, endpoint, nil)
+}
+
+// Post posts the json of a report to the endpoint, a path under the base URL or an absolute URL
+func (c *Client) Post(ctx context.Context, endpoint string, data []byte) error {
+	_, err := c.do(ctx, http.MethodPost, endpoint, data)
+	return err
+}
+
+func (c *Client) do(ctx context.Context, method, endpoint string, data []byte) ([]byte, error) {
+	u, err := c.url(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Accept", "application/json")
+		if c.UserAgent != "" {
+			req.Header.Set("User-Agent", c.UserAgent)
+		}
+		if method == http.MethodPost {
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-API-KEY", c.APIKey)
+		}
+
+		var sent atomic.Bool
+		if method != http.MethodGet {
+			req = req.WithContext(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+				WroteHeaders: func() { sent.Store(true) },
+			}))
+		}
+
+		body, retry, retryAfter, err := send(httpClient, req)
+		if err == nil {
+			return body, nil
+		}
+		if sent.Load() {
+			retry = false
+		}
+		if !retry || ctx.Err() != nil || attempt >= c.Retries {
+			return nil, err
+		}
+
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+// url resolves the endpoint against the base URL
+func (c *Client) url(endpoint string) (string, error) {
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL %q: %w", c.BaseURL, err)
+	}
+	if base.Path == "" || base.Path[len(base.Path)-1] != '/' {
+		base.Path += "/"
+	}
+
+	ref, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+// send makes the request, returning the body of a 2xx response or else the error, whether the
+// request can be retried and how long the server asked to wait before retrying
+func send(httpClient *http.Client, req *http.Request) ([]byte, bool, time.Duration, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, true, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, 0, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return body, false, 0, nil
+	}
+
+	statusErr := &StatusError{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       string(truncate(body, 512)),
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return nil, true, time.Duration(retryAfter) * time.Second, statusErr
+	}
+
+	return nil, false, 0, statusErr
+}
+
+func truncate(b []byte, n int) []byte {
+	if len(b) > n {
+		return b[:n]
+	}
+	return b
+}
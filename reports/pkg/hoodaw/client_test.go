@@ -0,0 +1,237 @@
+package hoodaw
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClient_HostedServices(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		retries    int
+		want       *HostedServices
+		wantStatus int
+		wantCalls  int
+	}{
+		{
+			name:      "decodes the report",
+			statuses:  []int{http.StatusOK},
+			want:      &HostedServices{UpdatedAt: "2024-01-2 10:4:5 UTC", HostedServices: []HostedService{{Namespace: "ns1", Cluster: "live"}}},
+			wantCalls: 1,
+		},
+		{
+			name:      "retries server errors",
+			statuses:  []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			retries:   2,
+			want:      &HostedServices{UpdatedAt: "2024-01-2 10:4:5 UTC", HostedServices: []HostedService{{Namespace: "ns1", Cluster: "live"}}},
+			wantCalls: 3,
+		},
+		{
+			name:       "gives up after the retries",
+			statuses:   []int{http.StatusBadGateway, http.StatusBadGateway},
+			retries:    1,
+			wantStatus: http.StatusBadGateway,
+			wantCalls:  2,
+		},
+		{
+			name:       "doesn't retry client errors",
+			statuses:   []int{http.StatusNotFound},
+			retries:    3,
+			wantStatus: http.StatusNotFound,
+			wantCalls:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/reports/hosted_services" || r.Header.Get("Accept") != "application/json" {
+					t.Errorf("got %s with Accept %q", r.URL.Path, r.Header.Get("Accept"))
+				}
+
+				status := tt.statuses[calls]
+				calls++
+				if status != http.StatusOK {
+					http.Error(w, http.StatusText(status), status)
+					return
+				}
+				io.WriteString(w, `{"updated_at": "2024-01-2 10:4:5 UTC", "namespace_details": [{"Name": "ns1", "Cluster": "live"}]}`)
+			}))
+			defer server.Close()
+
+			c := NewClient(server.URL + "/reports")
+			c.Retries = tt.retries
+			c.Backoff = time.Millisecond
+
+			got, err := c.HostedServices(context.Background())
+
+			var statusErr *StatusError
+			if tt.wantStatus != 0 {
+				if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus {
+					t.Errorf("HostedServices() error = %v, want a %d StatusError", err, tt.wantStatus)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HostedServices() = %+v, want %+v", got, tt.want)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("made %d requests, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestClient_StoredReports(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/reports/namespace_usage":
+			io.WriteString(w, `{"updated_at": "1", "data": [{"Name": "ns1", "Cluster": "live", "Used": {"Pods": 2}}]}`)
+		case "/reports/domain_reachability":
+			io.WriteString(w, `{"updated_at": "1", "domains": [{"Hostname": "old.service.justice.gov.uk", "Status": "dangling"}], "dangling": 1}`)
+		case "/reports/erroring_namespaces_history":
+			io.WriteString(w, `{"updated_at": "1", "runs": [{"updated_at": "2", "namespaces": [{"namespace": "ns1", "build_id": 3}]}], "trends": [{"Namespace": "ns1", "Status": "new"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL)
+	ctx := context.Background()
+
+	usage, err := c.NamespaceUsage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []NamespaceResources{{Name: "ns1", Cluster: "live", Used: ResourceCounts{Pods: 2}}}; !reflect.DeepEqual(usage.Namespaces, want) {
+		t.Errorf("NamespaceUsage() = %+v, want %+v", usage.Namespaces, want)
+	}
+
+	reachability, err := c.DomainReachability(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reachability.Dangling != 1 || len(reachability.Domains) != 1 || reachability.Domains[0].Status != "dangling" {
+		t.Errorf("DomainReachability() = %+v, want the dangling domain", reachability)
+	}
+
+	history, err := c.ErroringNamespacesHistory(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ErroringNamespacesHistory{
+		UpdatedAt: "1",
+		Runs:      []ErrorRun{{UpdatedAt: "2", Namespaces: []NamespaceError{{Namespace: "ns1", BuildID: 3}}}},
+		Trends:    []ErrorTrend{{Namespace: "ns1", Status: "new"}},
+	}
+	if !reflect.DeepEqual(history, want) {
+		t.Errorf("ErroringNamespacesHistory() = %+v, want %+v", history, want)
+	}
+}
+
+func TestClient_Post(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || string(body) != `{"a": 1}` {
+			t.Errorf("got %s %q", r.Method, body)
+		}
+		if r.Header.Get("X-API-KEY") != "key" {
+			http.Error(w, "bad api key", http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		apiKey  string
+		wantErr bool
+	}{
+		{"accepted", "key", false},
+		{"rejected", "wrong", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(server.URL)
+			c.APIKey = tt.apiKey
+
+			err := c.Post(context.Background(), "hosted_services", []byte(`{"a": 1}`))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Post() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// failingTransport fails every request before it is sent
+type failingTransport struct {
+	calls int
+}
+
+func (f *failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	f.calls++
+	return nil, errors.New("connection refused")
+}
+
+func TestClient_PostRetries(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "timed out writing the report", http.StatusGatewayTimeout)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL)
+	c.Backoff = time.Millisecond
+
+	// the report may have been written, so it isn't posted again
+	if err := c.Post(context.Background(), "hosted_services", []byte(`{}`)); err == nil {
+		t.Error("Post() of a 504 didn't return an error")
+	}
+	if calls != 1 {
+		t.Errorf("posted %d times after a 504, want 1", calls)
+	}
+
+	transport := &failingTransport{}
+	c.HTTPClient = &http.Client{Transport: transport}
+
+	if err := c.Post(context.Background(), "hosted_services", []byte(`{}`)); err == nil {
+		t.Error("Post() which couldn't connect didn't return an error")
+	}
+	if transport.calls != c.Retries+1 {
+		t.Errorf("posted %d times when it couldn't connect, want %d", transport.calls, c.Retries+1)
+	}
+}
+
+func TestClient_RetryAfter(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL)
+	c.Backoff = time.Millisecond
+
+	// the wait is cut short by the context rather than retrying straight away
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.Get(ctx, "helm_whatup"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if calls != 1 {
+		t.Errorf("made %d requests, want 1", calls)
+	}
+}
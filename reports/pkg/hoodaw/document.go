@@ -1,4 +1,13 @@
 /*
 Package hoodaw implements operators to interact with the How-out-of-date-are-we API.
+
+A Client gets each report as the same struct the web application decodes it into, e.g.
+
+	c := hoodaw.NewClient(hoodaw.DefaultURL)
+	services, err := c.HostedServices(ctx)
+
+and posts reports with its APIKey. It retries the gets which fail to connect or get a 429 or 5xx,
+and the posts which fail before they are sent, and returns a *StatusError for any other response
+which isn't a 2xx.
 */
 package hoodaw //import "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/reports/pkg/hoodaw"
@@ -1,14 +1,10 @@
 package hoodaw
 
 import (
-	"bytes"
-	"io/ioutil"
-	"net/http"
+	"context"
 	"time"
 )
 
-const host = "https://reports.cloud-platform.service.justice.gov.uk/"
-
 // resourceMap is used to store both string:string and string:map[string]string key
 // value pairs. The HOODAW API requires the first entry of map to contain a string:string,
 // the rest of the map consists of a primary key (string) with a value containing a
@@ -16,59 +12,28 @@ const host = "https://reports.cloud-platform.service.justice.gov.uk/"
 // All reports posting to the HOOWDAW API uses this variable when posting the report data
 type ResourceMap map[string]interface{}
 
-// QueryApi takes the name of an endpoint and uses the http package to
-// return a slice of bytes.
+// QueryApi takes the name of an endpoint of the production API and returns its json, failing
+// after 2 seconds without retrying.
+//
+// Deprecated: use Client.Get, or the typed method of the report.
 func QueryApi(endPoint string) ([]byte, error) {
-	client := &http.Client{
-		Timeout: time.Second * 2,
-	}
-
-	req, err := http.NewRequest(http.MethodGet, host+endPoint, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Add("User-Agent", "hoodaw-pkg")
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.Body != nil {
-		defer resp.Body.Close()
-	}
+	c := NewClient(DefaultURL)
+	c.HTTPClient.Timeout = 2 * time.Second
+	c.Retries = 0
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	return body, nil
+	return c.Get(context.Background(), endPoint)
 }
 
 // postToApi takes a slice of bytes as an argument and attempts to POST it to the REST API
 // provided by HOODAW. The slice of bytes should contain json using the guidelines outlined
 // by HOODAW i.e. the first entry in the key value pair should contain a string:string, which consists
-// of a string and the time POSTed.
+// of a string and the time POSTed. It fails when the API doesn't return a 2xx, and is only
+// retried when it fails before it is sent.
+//
+// Deprecated: use Client.Post.
 func PostToApi(jsonToPost []byte, hoodawApiKey, endPoint *string) error {
-	req, err := http.NewRequest("POST", *endPoint, bytes.NewBuffer(jsonToPost))
-	if err != nil {
-		return err
-	}
-
-	req.Header.Add("X-API-KEY", *hoodawApiKey)
-	req.Header.Add("Content-Type", "application/json")
-
-	client := &http.Client{}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-
-	defer resp.Body.Close()
+	c := NewClient(*endPoint)
+	c.APIKey = *hoodawApiKey
 
-	return nil
+	return c.Post(context.Background(), *endPoint, jsonToPost)
 }
@@ -0,0 +1,237 @@
+package hoodaw
+
+// The reports served by the HOODAW API. These are the only definition of the json of the
+// reports: the web application decodes them into these types, embedding them in its pages where
+// it adds fields of its own, so a change to a report changes both.
+
+// HostedServices is the hosted_services report
+type HostedServices struct {
+	UpdatedAt      string          `json:"updated_at"`
+	HostedServices []HostedService `json:"namespace_details"`
+}
+
+type HostedService struct {
+	Namespace      string        `json:"Name"`
+	Application    string        `json:"Application"`
+	BusinessUnit   string        `json:"BusinessUnit"`
+	TeamName       string        `json:"TeamName"`
+	SlackChannel   string        `json:"TeamSlackChannel"`
+	SourceCode     string        `json:"GithubURL"`
+	DomainNames    []string      `json:"DomainNames"`
+	Cluster        string        `json:"Cluster"`
+	DeploymentType string        `json:"DeploymentType"`
+	Ingresses      []IngressHost `json:"Ingresses"`
+}
+
+type IngressHost struct {
+	Hostname     string `json:"Hostname"`
+	Ingress      string `json:"Ingress"`
+	IngressClass string `json:"IngressClass"`
+	TLS          bool   `json:"TLS"`
+}
+
+// HelmReleases is the helm_releases report
+type HelmReleases struct {
+	UpdatedAt string    `json:"updated_at"`
+	Clusters  []Cluster `json:"clusters"`
+}
+
+type Cluster struct {
+	HelmReleases []HelmRelease `json:"apps"`
+	ClusterName  string        `json:"name"`
+}
+
+type HelmRelease struct {
+	Name             string `json:"name"`
+	Chart            string `json:"chart"`
+	Namespace        string `json:"namespace"`
+	InstalledVersion string `json:"installed_version"`
+	LatestVersion    string `json:"latest_version"`
+}
+
+// Costs is the namespace_costs report, keyed on the namespace
+type Costs struct {
+	UpdatedAt  string                   `json:"updated_at"`
+	Namespaces map[string]NamespaceCost `json:"namespace"`
+}
+
+type NamespaceCost struct {
+	Breakdown map[string]float32 `json:"breakdown"`
+	Total     float32            `json:"total"`
+	Clusters  []string           `json:"clusters"`
+}
+
+// NamespaceUsage is the namespace_usage report
+type NamespaceUsage struct {
+	UpdatedAt  string               `json:"updated_at"`
+	Namespaces []NamespaceResources `json:"data"`
+}
+
+type NamespaceResources struct {
+	Requested      ResourceCounts `json:"Requested"`
+	Used           ResourceCounts `json:"Used"`
+	Hardlimits     ResourceCounts `json:"Hardlimits"`
+	ContainerCount int            `json:"ContainerCount"`
+	Name           string         `json:"Name"`
+	Cluster        string         `json:"Cluster"`
+}
+
+type ResourceCounts struct {
+	CPU    int `json:"CPU"`
+	Memory int `json:"Memory"`
+	Pods   int `json:"Pods"`
+}
+
+// NamespaceError is a namespace which failed to apply in the apply-live pipeline
+type NamespaceError struct {
+	Namespace string `json:"namespace"`
+	Error     string `json:"error"`
+	BuildID   int    `json:"build_id"`
+}
+
+// ErroringNamespacesHistory is the erroring_namespaces_history report, the namespaces which
+// failed in each apply-live run, newest first, with the trend of each
+type ErroringNamespacesHistory struct {
+	UpdatedAt string       `json:"updated_at"`
+	Runs      []ErrorRun   `json:"runs"`
+	Trends    []ErrorTrend `json:"trends"`
+}
+
+// ErrorRun is the namespaces which failed in an apply-live run, identified by when its report was
+// written
+type ErrorRun struct {
+	UpdatedAt  string           `json:"updated_at"`
+	Namespaces []NamespaceError `json:"namespaces"`
+}
+
+// ErrorTrend is how long a namespace has been failing, or when it was fixed, Status is new,
+// failing or fixed. FirstSeen is the first run of its latest unbroken run of failures and
+// LastSeen the last run it failed in.
+type ErrorTrend struct {
+	Namespace           string `json:"Namespace"`
+	Status              string `json:"Status"`
+	FirstSeen           string `json:"FirstSeen"`
+	LastSeen            string `json:"LastSeen"`
+	FixedAt             string `json:"FixedAt"`
+	ConsecutiveFailures int    `json:"ConsecutiveFailures"`
+	Error               string `json:"Error"`
+	BuildID             int    `json:"BuildID"`
+}
+
+// Domains is the live_one_domains report
+type Domains struct {
+	UpdatedAt string   `json:"updated_at"`
+	Data      []Domain `json:"live_one_domains"`
+}
+
+type Domain struct {
+	Cluster           string `json:"cluster"`
+	Namespace         string `json:"namespace"`
+	IngressName       string `json:"ingress"`
+	URL               string `json:"hostname"`
+	Pattern           string `json:"pattern"`
+	Deadline          string `json:"deadline"`
+	CreationTimestamp string `json:"CreatedAt"`
+}
+
+// DomainReachability is the domain_reachability report, the domain names of the hosted services
+// resolved and requested over HTTPS, with the number which are dangling
+type DomainReachability struct {
+	UpdatedAt string            `json:"updated_at"`
+	Domains   []ReachableDomain `json:"domains"`
+	Dangling  int               `json:"dangling"`
+}
+
+// ReachableDomain is a domain name of a namespace as checked by the domain-reachability report.
+// Status is dangling when the domain, or the name it is a CNAME of, no longer resolves.
+type ReachableDomain struct {
+	Cluster    string   `json:"Cluster"`
+	Namespace  string   `json:"Namespace"`
+	Hostname   string   `json:"Hostname"`
+	Target     string   `json:"Target"`
+	Addresses  []string `json:"Addresses"`
+	StatusCode int      `json:"StatusCode"`
+	LatencyMs  int64    `json:"LatencyMs"`
+	Status     string   `json:"Status"`
+	Error      string   `json:"Error"`
+}
+
+// NamespaceCompliance is the namespace_compliance report
+type NamespaceCompliance struct {
+	UpdatedAt         string                  `json:"updated_at"`
+	Namespaces        []NonCompliantNamespace `json:"namespace_compliance"`
+	NamespacesChecked int                     `json:"namespaces_checked"`
+}
+
+type NonCompliantNamespace struct {
+	Name             string   `json:"Name"`
+	Cluster          string   `json:"Cluster"`
+	TeamName         string   `json:"TeamName"`
+	TeamSlackChannel string   `json:"TeamSlackChannel"`
+	Issues           []string `json:"Issues"`
+}
+
+// CertificateExpiry is the certificate_expiry report
+type CertificateExpiry struct {
+	UpdatedAt    string        `json:"updated_at"`
+	Certificates []Certificate `json:"certificates"`
+}
+
+type Certificate struct {
+	Cluster     string   `json:"Cluster"`
+	Namespace   string   `json:"Namespace"`
+	Host        string   `json:"Host"`
+	Ingress     string   `json:"Ingress"`
+	Secret      string   `json:"Secret"`
+	Certificate string   `json:"Certificate"`
+	Issuer      string   `json:"Issuer"`
+	IssuerRef   string   `json:"IssuerRef"`
+	SANs        []string `json:"SANs"`
+	NotAfter    string   `json:"NotAfter"`
+	Error       string   `json:"Error"`
+}
+
+// InfrastructureDeployments is the infrastructure_deployments report
+type InfrastructureDeployments struct {
+	UpdatedAt   string               `json:"updated_at"`
+	Deployments []MonthlyDeployments `json:"deployments"`
+	Metrics     DoraMetrics          `json:"metrics"`
+	Weeks       []DeploymentWeek     `json:"weeks"`
+	Failures    []DeploymentFailure  `json:"failures"`
+	Errors      []string             `json:"errors"`
+}
+
+type MonthlyDeployments struct {
+	Date     string `json:"date"`
+	Deployed string `json:"deployed"`
+	Failed   string `json:"failed"`
+}
+
+type DoraMetrics struct {
+	PeriodStart        string  `json:"period_start"`
+	PeriodEnd          string  `json:"period_end"`
+	Deployments        int     `json:"deployments"`
+	Failures           int     `json:"failures"`
+	DeploymentsPerWeek float64 `json:"deployments_per_week"`
+	LeadTimeHours      float64 `json:"lead_time_hours"`
+	ChangeFailureRate  float64 `json:"change_failure_rate"`
+	TimeToRestoreHours float64 `json:"time_to_restore_hours"`
+}
+
+type DeploymentWeek struct {
+	Start         string  `json:"start"`
+	Deployments   int     `json:"deployments"`
+	Failures      int     `json:"failures"`
+	LeadTimeHours float64 `json:"lead_time_hours"`
+}
+
+type DeploymentFailure struct {
+	Repository   string  `json:"repository"`
+	Number       int     `json:"number"`
+	Title        string  `json:"title"`
+	URL          string  `json:"url"`
+	MergedAt     string  `json:"merged_at"`
+	Reason       string  `json:"reason"`
+	RestoredBy   string  `json:"restored_by"`
+	RestoreHours float64 `json:"restore_hours"`
+}
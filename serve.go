@@ -0,0 +1,168 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+
+	lib "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/lib"
+	utils "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/utils"
+)
+
+// serve runs the web application which renders the report json stored in S3
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	bucket := fs.String("bucket", "cloud-platform-hoodaw-reports", "AWS S3 bucket for hoodaw json reports")
+	errorNsBucket := fs.String("error-ns-bucket", "cloud-platform-concourse-environments-live-reports", "AWS S3 bucket for the apply-live erroring namespaces report")
+	buildURLTemplate := fs.String("build-url-template", "https://concourse.cloud-platform.service.justice.gov.uk/builds/{build_id}", "URL of an apply-live build, {build_id} and {namespace} are replaced with those of the erroring namespace")
+	errorNsHistory := fs.Int("error-ns-history", 10, "Number of apply-live runs to show the erroring namespaces history of, from the versions kept by the bucket")
+	searchRefresh := fs.Duration("search-refresh", 5*time.Minute, "How often to check the reports for updates to rebuild the search index from")
+	freshnessConfig := fs.String("freshness-config", "config/report_freshness.yaml", "Yaml file of how many hours after each report was written it is stale")
+	fs.Parse(args)
+
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
+	freshness, err := lib.LoadFreshnessConfig(*freshnessConfig)
+	if err != nil {
+		return err
+	}
+
+	client, err := utils.S3Client("eu-west-2")
+	if err != nil {
+		return fmt.Errorf("unable to create the s3 client: %w", err)
+	}
+
+	// the readiness probe keeps the pages from being served until the buckets can be read
+	for _, b := range []string{*bucket, *errorNsBucket} {
+		exists, err := utils.CheckBucketExists(client, b)
+		if err != nil {
+			slog.Error("unable to check bucket", "bucket", b, "error", err)
+		} else if !exists {
+			slog.Error("bucket does not exist", "bucket", b)
+		}
+	}
+
+	buildInfo := lib.BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		GoVersion: runtime.Version(),
+		StartedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	readiness := lib.NewReadiness(client, *bucket, *errorNsBucket)
+	freshnessChecker := lib.NewFreshnessChecker(client, *bucket, *errorNsBucket, freshness)
+
+	metrics := lib.NewMetrics(freshnessChecker)
+
+	index := lib.NewSearchIndex(client, *bucket, *errorNsBucket)
+	index.OnRefresh(metrics.UpdateReports)
+	go index.RefreshEvery(*searchRefresh)
+
+	http.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		lib.HealthzPage(w)
+	})
+
+	http.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		lib.ReadyzPage(w, r, readiness)
+	})
+
+	http.Handle("GET /metrics", metrics.Handler())
+
+	http.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		lib.VersionPage(w, buildInfo)
+	})
+
+	http.Handle("/static/",
+		http.StripPrefix("/static/",
+			http.FileServer(http.Dir("lib/static"))))
+
+	http.HandleFunc("/hosted_services", withQuery(func(w http.ResponseWriter, r *http.Request, format lib.Format, query lib.Query) error {
+		return lib.HostedServicesPage(w, *bucket, query, format, client)
+	}))
+
+	http.HandleFunc("/helm_whatup", withQuery(func(w http.ResponseWriter, r *http.Request, format lib.Format, query lib.Query) error {
+		return lib.HelmReleasesPage(w, *bucket, query, format, client)
+	}))
+
+	http.HandleFunc("/costs_by_namespace", withQuery(func(w http.ResponseWriter, r *http.Request, format lib.Format, query lib.Query) error {
+		return lib.NamespaceCostsPage(w, *bucket, query, format, client)
+	}))
+
+	http.HandleFunc("/erroring_namespaces", withQuery(func(w http.ResponseWriter, r *http.Request, format lib.Format, query lib.Query) error {
+		return lib.ErroredNamespacesPage(w, *errorNsBucket, *bucket, *buildURLTemplate, *errorNsHistory, query, format, client)
+	}))
+
+	http.HandleFunc("GET /namespace/{namespace}", withFormat(func(w http.ResponseWriter, r *http.Request, format lib.Format) error {
+		namespace := r.PathValue("namespace")
+		return lib.NamespaceUsagePage(w, *bucket, *errorNsBucket, namespace, r.URL.Query().Get("cluster"), format, client)
+	}))
+
+	http.HandleFunc("GET /freshness", withQuery(func(w http.ResponseWriter, r *http.Request, format lib.Format, query lib.Query) error {
+		return lib.FreshnessPage(w, r, query, format, freshnessChecker)
+	}))
+
+	http.HandleFunc("GET /team/{team}", withFormat(func(w http.ResponseWriter, r *http.Request, format lib.Format) error {
+		return lib.TeamPage(w, *bucket, *errorNsBucket, r.PathValue("team"), format, client)
+	}))
+
+	http.HandleFunc("GET /live_one_domains", withQuery(func(w http.ResponseWriter, r *http.Request, format lib.Format, query lib.Query) error {
+		return lib.LiveOneDomainsPage(w, *bucket, query, format, client)
+	}))
+
+	http.HandleFunc("GET /namespace_compliance", withQuery(func(w http.ResponseWriter, r *http.Request, format lib.Format, query lib.Query) error {
+		return lib.NamespaceCompliancePage(w, *bucket, query, format, client)
+	}))
+
+	http.HandleFunc("GET /certificate_expiry", withQuery(func(w http.ResponseWriter, r *http.Request, format lib.Format, query lib.Query) error {
+		return lib.CertificateExpiryPage(w, *bucket, query, format, client)
+	}))
+
+	http.HandleFunc("GET /infrastructure_deployments", withFormat(func(w http.ResponseWriter, r *http.Request, format lib.Format) error {
+		return lib.InfrastructureDeploymentsPage(w, *bucket, format, client)
+	}))
+
+	http.HandleFunc("GET /reports/{report}", withFormat(func(w http.ResponseWriter, r *http.Request, format lib.Format) error {
+		return lib.ReportDataPage(w, *bucket, r.PathValue("report"), format, client)
+	}))
+
+	http.HandleFunc("GET /search", withQuery(func(w http.ResponseWriter, r *http.Request, format lib.Format, query lib.Query) error {
+		return lib.SearchPage(w, index, r.URL.Query().Get("q"), query, format)
+	}))
+
+	fmt.Printf("Listening on port %s ...\n", *addr)
+	if err := http.ListenAndServe(*addr, metrics.Instrument(http.DefaultServeMux)); err != nil {
+		return fmt.Errorf("error starting server: %w", err)
+	}
+
+	return nil
+}
+
+// withFormat negotiates the format of a page from the request, rejecting unknown formats, and
+// writes the error the page fails with
+func withFormat(page func(w http.ResponseWriter, r *http.Request, format lib.Format) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, err := lib.NegotiateFormat(r)
+		if err != nil {
+			lib.WriteError(w, r, lib.FormatJSON, &lib.StatusError{Status: http.StatusBadRequest, Err: err})
+			return
+		}
+		if err := page(w, r, format); err != nil {
+			lib.WriteError(w, r, format, err)
+		}
+	}
+}
+
+// withQuery also parses the filtering, sorting and pagination of the rows of the report of a page
+func withQuery(page func(w http.ResponseWriter, r *http.Request, format lib.Format, query lib.Query) error) http.HandlerFunc {
+	return withFormat(func(w http.ResponseWriter, r *http.Request, format lib.Format) error {
+		query, err := lib.ParseQuery(r.URL.Query(), format)
+		if err != nil {
+			return &lib.StatusError{Status: http.StatusBadRequest, Err: err}
+		}
+		return page(w, r, format, query)
+	})
+}
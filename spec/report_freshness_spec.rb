@@ -0,0 +1,84 @@
+require "spec_helper"
+
+describe ReportFreshness do
+  let(:logger) { double(Sinatra::CommonLogger) }
+  let(:now) { Time.parse("2020-07-14 12:00:00") }
+  let(:go_freshness_url) { "" }
+
+  let(:config) {
+    {
+      "default_max_age_hours" => 26,
+      "ruby" => {
+        "documentation" => 12,
+      },
+    }
+  }
+
+  subject(:freshness) {
+    described_class.new(logger: logger, config: config, now: now, go_freshness_url: go_freshness_url)
+  }
+
+  before do
+    allow(logger).to receive(:info)
+    described_class.go_cache.clear
+  end
+
+  it "uses the maximum age of the report" do
+    expect(freshness.max_age_hours("documentation")).to eq(12)
+  end
+
+  it "uses the default maximum age for other reports" do
+    expect(freshness.max_age_hours("helm_whatup")).to eq(26)
+  end
+
+  it "finds the reports updated longer ago than their maximum age, or never" do
+    reports = {
+      "documentation" => "2020-07-13 23:00:00",
+      "helm_whatup" => "2020-07-13 23:00:00",
+      "terraform_modules" => "2020-07-12 12:00:00",
+      "orphaned_statefiles" => "",
+    }
+    expect(freshness.stale_reports(reports)).to eq(["documentation", "orphaned_statefiles", "terraform_modules"])
+  end
+
+  context "without the go web application" do
+    it "has no go stale reports" do
+      expect(freshness.go_stale_count).to eq(0)
+    end
+  end
+
+  context "with the go web application" do
+    let(:go_freshness_url) { "http://localhost:8080/freshness" }
+
+    it "counts its stale reports" do
+      allow(URI).to receive(:open).and_return({"stale" => 2, "reports" => []}.to_json)
+      expect(freshness.go_stale_count).to eq(2)
+    end
+
+    it "has no go stale count when it can't be read" do
+      allow(URI).to receive(:open).and_raise(Errno::ECONNREFUSED)
+      expect(freshness.go_stale_count).to be_nil
+    end
+
+    it "reads it with short timeouts" do
+      expect(URI).to receive(:open).with(go_freshness_url, hash_including(open_timeout: 2, read_timeout: 3)).and_return({"stale" => 1}.to_json)
+      freshness.go_stale_count
+    end
+
+    it "reads it at most once a minute" do
+      expect(URI).to receive(:open).once.and_return({"stale" => 2}.to_json)
+      expect(freshness.go_stale_count).to eq(2)
+
+      later = described_class.new(logger: logger, config: config, now: now + 59, go_freshness_url: go_freshness_url)
+      expect(later.go_stale_count).to eq(2)
+    end
+
+    it "reads it again after a minute" do
+      allow(URI).to receive(:open).and_return({"stale" => 2}.to_json, {"stale" => 3}.to_json)
+      freshness.go_stale_count
+
+      later = described_class.new(logger: logger, config: config, now: now + 61, go_freshness_url: go_freshness_url)
+      expect(later.go_stale_count).to eq(3)
+    end
+  end
+end
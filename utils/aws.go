@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"log"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -114,6 +115,19 @@ func ArchiveFile(client *s3.Client, bucketName, objectKey string) error {
 	return nil
 }
 
+// S3FileLastModified returns when a file in S3 was last written, without downloading it
+func S3FileLastModified(ctx context.Context, client *s3.Client, bucketName, objectKey string) (time.Time, error) {
+	output, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return aws.ToTime(output.LastModified), nil
+}
+
 // ImportS3File downloads a file from S3 and returns the file content and the last modified timestamp
 func ImportS3File(client *s3.Client, bucketName, objectKey string) ([]byte, string, error) {
 	output, err := client.GetObject(ctx, &s3.GetObjectInput{
@@ -133,3 +147,57 @@ func ImportS3File(client *s3.Client, bucketName, objectKey string) ([]byte, stri
 
 	return buf.Bytes(), fileTimeStamp, nil
 }
+
+// S3FileVersion is a version of an object in a versioned bucket
+type S3FileVersion struct {
+	VersionID    string
+	LastModified time.Time
+}
+
+// ListS3FileVersions lists up to limit of the most recent versions of a file in a versioned S3
+// bucket, newest first. An unversioned bucket returns just the current file, with the version "null".
+func ListS3FileVersions(client *s3.Client, bucketName, objectKey string, limit int) ([]S3FileVersion, error) {
+	output, err := client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+		Bucket:  aws.String(bucketName),
+		Prefix:  aws.String(objectKey),
+		MaxKeys: aws.Int32(int32(limit)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]S3FileVersion, 0)
+	for _, v := range output.Versions {
+		// the prefix also matches longer keys
+		if aws.ToString(v.Key) != objectKey || len(versions) == limit {
+			continue
+		}
+
+		versions = append(versions, S3FileVersion{
+			VersionID:    aws.ToString(v.VersionId),
+			LastModified: aws.ToTime(v.LastModified),
+		})
+	}
+
+	return versions, nil
+}
+
+// ImportS3FileVersion downloads a version of a file from a versioned S3 bucket
+func ImportS3FileVersion(client *s3.Client, bucketName, objectKey, versionID string) ([]byte, error) {
+	obj, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(objectKey),
+		VersionId: aws.String(versionID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Body.Close()
+
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(obj.Body); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/ministryofjustice/cloud-platform-environments/pkg/authenticate"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// KubeClient returns a clientset for the cluster context. The kubeconfig is fetched from the S3
+// bucket when one is set, otherwise the local kubeconfig file at kubeCfgPath is used so reports
+// can be run against a local (e.g. kind) cluster.
+func KubeClient(bucket, kubeconfig, region, kubeCfgPath, clusterCtx string) (*kubernetes.Clientset, error) {
+	if bucket == "" {
+		return authenticate.CreateClientFromConfigFile(kubeCfgPath, clusterCtx)
+	}
+
+	return authenticate.CreateClientFromS3Bucket(bucket, kubeconfig, region, clusterCtx)
+}
+
+// KubeConfig returns the client config for the cluster context, for reports which need more than
+// a clientset, e.g. a dynamic client for custom resources. The kubeconfig is read in the same
+// way as KubeClient.
+func KubeConfig(bucket, kubeconfig, region, kubeCfgPath, clusterCtx string) (*rest.Config, error) {
+	if bucket != "" {
+		kubeCfgPath = filepath.Join("/", "tmp", "kubeconfig")
+		if err := authenticate.KubeConfigFromS3Bucket(bucket, kubeconfig, region, kubeCfgPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return authenticate.NewConfigFromContext(kubeCfgPath, clusterCtx)
+}
+
+// Contexts is the comma separated list of kube contexts given to the --context flag of the
+// reports which run against every cluster, e.g. live.cloud-platform.service.justice.gov.uk,manager
+type Contexts []string
+
+func (c *Contexts) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *Contexts) Set(value string) error {
+	*c = nil
+	for _, ctx := range strings.Split(value, ",") {
+		if ctx = strings.TrimSpace(ctx); ctx != "" {
+			*c = append(*c, ctx)
+		}
+	}
+
+	if len(*c) == 0 {
+		return errors.New("at least one context is required")
+	}
+
+	return nil
+}
+
+// ClusterName returns the name reports tag their results with for a kube context,
+// e.g. live for live.cloud-platform.service.justice.gov.uk
+func ClusterName(ctx string) string {
+	return strings.Split(ctx, ".")[0]
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContextsSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    Contexts
+		wantErr bool
+	}{
+		{"single context", "live.cloud-platform.service.justice.gov.uk", Contexts{"live.cloud-platform.service.justice.gov.uk"}, false},
+		{"list of contexts", "live.cloud-platform.service.justice.gov.uk, manager", Contexts{"live.cloud-platform.service.justice.gov.uk", "manager"}, false},
+		{"empty", " , ", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Contexts{"default"}
+			err := c.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Contexts.Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(c, tt.want) {
+				t.Errorf("Contexts.Set() = %v, want %v", c, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterName(t *testing.T) {
+	if got := ClusterName("live.cloud-platform.service.justice.gov.uk"); got != "live" {
+		t.Errorf("ClusterName() = %v, want live", got)
+	}
+}
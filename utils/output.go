@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Output is a destination the json of a report is written to. Check is called before the report
+// is generated, so an output which can't be written to fails the report straight away.
+type Output interface {
+	Check() error
+	Write(data []byte) error
+	String() string
+}
+
+// S3Output uploads the report to a key in an S3 bucket
+type S3Output struct {
+	Bucket string
+	Key    string
+}
+
+// FileOutput writes the report to a local file
+type FileOutput struct {
+	Path string
+}
+
+// StdoutOutput prints the report
+type StdoutOutput struct{}
+
+// HTTPOutput posts the report to an ingest endpoint of the HOODAW API
+type HTTPOutput struct {
+	URL    string
+	APIKey string
+}
+
+// ParseOutput returns the output for the --output flag of a report, which can be:
+//
+//	s3                        the key of the report in the default bucket
+//	s3://bucket or s3://bucket/key
+//	stdout or -
+//	http(s)://host/endpoint   posted with the API key
+//	a .json file path, or any other path as a directory to write the key to
+func ParseOutput(output, defaultBucket, key, apiKey string) (Output, error) {
+	switch {
+	case output == "" || output == "s3":
+		if defaultBucket == "" {
+			return nil, fmt.Errorf("no hoodaw bucket set to write %s to", key)
+		}
+		return &S3Output{Bucket: defaultBucket, Key: key}, nil
+	case strings.HasPrefix(output, "s3://"):
+		bucket, objectKey, _ := strings.Cut(strings.TrimPrefix(output, "s3://"), "/")
+		if bucket == "" {
+			return nil, fmt.Errorf("no bucket in output %s", output)
+		}
+		if objectKey == "" {
+			objectKey = key
+		}
+		return &S3Output{Bucket: bucket, Key: objectKey}, nil
+	case output == "stdout" || output == "-":
+		return &StdoutOutput{}, nil
+	case strings.HasPrefix(output, "http://") || strings.HasPrefix(output, "https://"):
+		u, err := url.Parse(output)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid ingest URL %s", output)
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("no API key to post to %s, set --api-key or HOODAW_API_KEY", output)
+		}
+		return &HTTPOutput{URL: output, APIKey: apiKey}, nil
+	}
+
+	if filepath.Ext(output) == ".json" {
+		return &FileOutput{Path: output}, nil
+	}
+
+	return &FileOutput{Path: filepath.Join(output, key)}, nil
+}
+
+func (o *S3Output) String() string { return "s3://" + o.Bucket + "/" + o.Key }
+
+// Check checks the bucket exists and can be reached with the AWS credentials
+func (o *S3Output) Check() error {
+	client, err := S3Client("eu-west-2")
+	if err != nil {
+		return fmt.Errorf("unable to create an S3 client: %w", err)
+	}
+
+	exists, err := CheckBucketExists(client, o.Bucket)
+	if err != nil {
+		return fmt.Errorf("unable to reach bucket %s: %w", o.Bucket, err)
+	}
+
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", o.Bucket)
+	}
+
+	return nil
+}
+
+func (o *S3Output) Write(data []byte) error {
+	client, err := S3Client("eu-west-2")
+	if err != nil {
+		return fmt.Errorf("unable to create an S3 client: %w", err)
+	}
+
+	if err := ExportToS3(client, o.Bucket, o.Key, data); err != nil {
+		return fmt.Errorf("failed to upload to %s: %w", o, err)
+	}
+
+	return nil
+}
+
+func (o *FileOutput) String() string { return o.Path }
+
+// Check creates the directory of the file and checks a file can be written to it
+func (o *FileOutput) Check() error {
+	dir := filepath.Dir(o.Path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, ".hoodaw-check-*")
+	if err != nil {
+		return fmt.Errorf("unable to write to %s: %w", dir, err)
+	}
+	f.Close()
+
+	return os.Remove(f.Name())
+}
+
+func (o *FileOutput) Write(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(o.Path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(o.Path, data, 0o644)
+}
+
+func (o *StdoutOutput) String() string { return "stdout" }
+
+func (o *StdoutOutput) Check() error { return nil }
+
+func (o *StdoutOutput) Write(data []byte) error {
+	_, err := os.Stdout.Write(data)
+	return err
+}
+
+func (o *HTTPOutput) String() string { return o.URL }
+
+// Check makes a HEAD request to the endpoint to check it can be reached, any response will do
+// as the endpoint only has to accept the POST
+func (o *HTTPOutput) Check() error {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	resp, err := client.Head(o.URL)
+	if err != nil {
+		return fmt.Errorf("unable to reach %s: %w", o.URL, err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Write posts the report with the X-API-KEY header and fails on a non 2xx response
+func (o *HTTPOutput) Write(data []byte) error {
+	req, err := http.NewRequest(http.MethodPost, o.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Add("X-API-KEY", o.APIKey)
+	req.Header.Add("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("posting to %s returned %s: %s", o.URL, resp.Status, body)
+	}
+
+	return nil
+}
+
+// SummariseReport describes the report json for a dry run: its size, updated_at
+// and the number of items held under each of the other top level keys
+func SummariseReport(data []byte) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Sprintf("%d bytes, not a json object: %s", len(data), err)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	summary := []string{fmt.Sprintf("%d bytes", len(data))}
+	for _, k := range keys {
+		var value interface{}
+		json.Unmarshal(fields[k], &value)
+
+		switch v := value.(type) {
+		case []interface{}:
+			summary = append(summary, fmt.Sprintf("%s: %d items", k, len(v)))
+		case map[string]interface{}:
+			summary = append(summary, fmt.Sprintf("%s: %d entries", k, len(v)))
+		default:
+			summary = append(summary, fmt.Sprintf("%s: %v", k, v))
+		}
+	}
+
+	return strings.Join(summary, ", ")
+}
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    Output
+		wantErr bool
+	}{
+		{"default bucket", "s3", &S3Output{Bucket: "hoodaw", Key: "report.json"}, false},
+		{"bucket only", "s3://other", &S3Output{Bucket: "other", Key: "report.json"}, false},
+		{"bucket and key", "s3://other/dev/report.json", &S3Output{Bucket: "other", Key: "dev/report.json"}, false},
+		{"no bucket", "s3://", nil, true},
+		{"stdout", "-", &StdoutOutput{}, false},
+		{"ingest url", "https://reports.example.com/hosted_services", &HTTPOutput{URL: "https://reports.example.com/hosted_services", APIKey: "key"}, false},
+		{"ingest url without a host", "https:///hosted_services", nil, true},
+		{"json file", "out/hosted.json", &FileOutput{Path: "out/hosted.json"}, false},
+		{"directory", "data", &FileOutput{Path: "data/report.json"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOutput(tt.output, "hoodaw", "report.json", "key")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseOutput() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOutput_noAPIKey(t *testing.T) {
+	_, err := ParseOutput("https://reports.example.com/hosted_services", "hoodaw", "report.json", "")
+	if err == nil || !strings.Contains(err.Error(), "no API key") {
+		t.Errorf("ParseOutput() error = %v, want a missing API key error", err)
+	}
+}
+
+func TestHTTPOutput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Header.Get("X-API-KEY") != "key":
+			http.Error(w, "bad api key", http.StatusForbidden)
+		}
+	}))
+
+	tests := []struct {
+		name         string
+		apiKey       string
+		wantWriteErr string
+	}{
+		{"posted", "key", ""},
+		{"rejected", "wrong", "403 Forbidden: bad api key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &HTTPOutput{URL: server.URL + "/hosted_services", APIKey: tt.apiKey}
+			if err := o.Check(); err != nil {
+				t.Errorf("Check() error = %v", err)
+			}
+
+			err := o.Write([]byte("{}"))
+			if (err != nil) != (tt.wantWriteErr != "") || (err != nil && !strings.Contains(err.Error(), tt.wantWriteErr)) {
+				t.Errorf("Write() error = %v, want %q", err, tt.wantWriteErr)
+			}
+		})
+	}
+
+	server.Close()
+	o := &HTTPOutput{URL: server.URL + "/hosted_services", APIKey: "key"}
+	if err := o.Check(); err == nil {
+		t.Error("Check() of a closed server succeeded")
+	}
+}
+
+func TestFileOutput_Check(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"new directory", filepath.Join(dir, "reports", "report.json"), false},
+		{"directory is a file", filepath.Join(file, "report.json"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &FileOutput{Path: tt.path}
+			if err := o.Check(); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(dir, "reports"))
+	if len(entries) != 0 {
+		t.Errorf("Check() left %d files behind", len(entries))
+	}
+}
+
+func TestSummariseReport(t *testing.T) {
+	data := []byte(`{"updated_at": "2024-01-2 10:4:5 UTC", "namespace_details": [{}, {}], "totals": {"a": 1}}`)
+	want := "89 bytes, namespace_details: 2 items, totals: 1 entries, updated_at: 2024-01-2 10:4:5 UTC"
+
+	if got := SummariseReport(data); got != want {
+		t.Errorf("SummariseReport() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,22 @@
+package utils
+
+import "flag"
+
+// Report is implemented by each of the Go reports so they can be run as
+// subcommands of the hoodaw binary, e.g. `hoodaw report hosted-services`.
+type Report interface {
+	// Name returns the subcommand name of the report, e.g. "hosted-services"
+	Name() string
+	// Key returns the object key the report json is stored under, e.g. "hosted_services.json"
+	Key() string
+	// AddFlags registers the report specific flags on the flagset
+	AddFlags(fs *flag.FlagSet)
+	// Generate gathers the report data and returns it as json
+	Generate() ([]byte, error)
+}
+
+// HoodawReader is implemented by the reports which read other reports from the hoodaw bucket. They
+// are given the bucket set by --hoodaw-bucket, the one the reports are written to, before Generate.
+type HoodawReader interface {
+	SetHoodawBucket(bucket string)
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// HoodawSource is source when it is set, or else the report with the key in the hoodaw bucket. It
+// fails without either, naming the flag which sets the source.
+func HoodawSource(source, bucket, key, flag string) (string, error) {
+	if source != "" {
+		return source, nil
+	}
+
+	if bucket == "" {
+		return "", fmt.Errorf("--%s or --hoodaw-bucket required", flag)
+	}
+
+	return "s3://" + bucket + "/" + key, nil
+}
+
+// ReadSource reads the json a report is generated from, which can be:
+//
+//	s3://bucket/key
+//	http(s)://host/path       requested as application/json
+//	a local file path
+//
+// A missing S3 key or file returns an error matching fs.ErrNotExist.
+func ReadSource(source string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(source, "s3://"):
+		bucket, key, _ := strings.Cut(strings.TrimPrefix(source, "s3://"), "/")
+		if bucket == "" || key == "" {
+			return nil, fmt.Errorf("no bucket or key in %s", source)
+		}
+
+		client, err := S3Client("eu-west-2")
+		if err != nil {
+			return nil, err
+		}
+
+		data, _, err := ImportS3File(client, bucket, key)
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, fmt.Errorf("%s: %w", source, fs.ErrNotExist)
+		}
+		return data, err
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		req, err := http.NewRequest(http.MethodGet, source, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+
+		client := &http.Client{Timeout: 30 * time.Second}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("%s returned %s", source, resp.Status)
+		}
+
+		return io.ReadAll(resp.Body)
+	}
+
+	return os.ReadFile(source)
+}
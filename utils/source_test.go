@@ -0,0 +1,28 @@
+package utils
+
+import "testing"
+
+func TestHoodawSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		bucket  string
+		want    string
+		wantErr bool
+	}{
+		{"source", "history.json", "hoodaw", "history.json", false},
+		{"hoodaw bucket", "", "hoodaw", "s3://hoodaw/report.json", false},
+		{"neither", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HoodawSource(tt.source, tt.bucket, "report.json", "history")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HoodawSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("HoodawSource() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
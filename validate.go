@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	lib "github.com/ministryofjustice/cloud-platform-how-out-of-date-are-we/lib"
+)
+
+// validate checks report json files, e.g. written by `hoodaw report <name> --output <dir>`,
+// can be read by the web application before they are uploaded
+func validate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	key := fs.String("key", "", "Report key to validate the files as, e.g. hosted_services.json. Defaults to the file name")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return errors.New("at least one report json file is required")
+	}
+
+	failed := 0
+	for _, file := range fs.Args() {
+		reportKey := *key
+		if reportKey == "" {
+			reportKey = filepath.Base(file)
+		}
+
+		data, err := os.ReadFile(file)
+		if err == nil {
+			err = lib.ValidateReport(reportKey, data)
+		}
+
+		if err != nil {
+			fmt.Printf("FAIL %s: %s\n", file, err)
+			failed++
+			continue
+		}
+
+		fmt.Printf("ok   %s\n", file)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d report files are invalid", failed, fs.NArg())
+	}
+
+	return nil
+}